@@ -18,8 +18,8 @@ var (
 
 func getStore() (*storage.Store, error) {
 	initOnce.Do(func() {
-		// Use /tmp for ephemeral storage on Vercel
-		s, err := storage.NewStoreWithWAL("/tmp/grassdb_vercel.wal")
+		// Use /tmp for ephemeral storage on Vercel; it doesn't survive a restart, so skip fsync
+		s, err := storage.NewStoreWithWAL("/tmp/grassdb_vercel.wal", nil, storage.WALConfig{SyncMode: storage.SyncNever})
 		if err != nil {
 			initErr = err
 			return
@@ -33,7 +33,7 @@ func getStore() (*storage.Store, error) {
 func Handler(w http.ResponseWriter, r *http.Request) {
 	// CORS
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 	if r.Method == "OPTIONS" {
@@ -69,6 +69,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasSuffix(path, "/delete") || r.Method == http.MethodDelete {
+		handleDelete(w, r, st)
+		return
+	}
+
 	// Fallback: Dispatch by method if path is ambiguous
 	if r.Method == http.MethodGet {
 		handleGet(w, r, st)
@@ -108,7 +113,26 @@ func handleSet(w http.ResponseWriter, r *http.Request, st *storage.Store) {
 		return
 	}
 
-	st.Set(req.Key, req.Value)
+	if err := st.Set(0, req.Key, req.Value); err != nil { // No Raft log behind this store
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func handleDelete(w http.ResponseWriter, r *http.Request, st *storage.Store) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+
+	if err := st.Delete(0, key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
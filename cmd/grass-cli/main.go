@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"grassdb/pkg/client"
+
+	pb "github.com/ranjan42/grassdb/proto"
 )
 
 func main() {
 	peersStr := flag.String("peers", "localhost:50051,localhost:50052,localhost:50053", "Comma-separated list of peer addresses")
+	consistency := flag.String("consistency", "linearizable", "Read consistency for get: linearizable, lease or stale")
+	maxStaleness := flag.Duration("max-staleness", 0, "For stale reads, the maximum time since the node heard from the leader (0 = unbounded)")
 	flag.Parse()
 
 	args := flag.Args()
@@ -19,6 +24,14 @@ func main() {
 		fmt.Println("Commands:")
 		fmt.Println("  set <key> <value>")
 		fmt.Println("  get <key>")
+		fmt.Println("  del <key>")
+		fmt.Println("  snapshot")
+		fmt.Println("  status")
+		fmt.Println("  add-member <id> <addr>")
+		fmt.Println("  add-learner <id> <addr>")
+		fmt.Println("  promote-member <id>")
+		fmt.Println("  remove-member <id>")
+		fmt.Println("  transfer-leader <id>")
 		os.Exit(1)
 	}
 
@@ -46,7 +59,18 @@ func main() {
 			os.Exit(1)
 		}
 		key := args[1]
-		val, found, err := c.Get(key)
+		var opts []client.ReadOption
+		switch *consistency {
+		case "linearizable":
+		case "lease":
+			opts = append(opts, client.Lease())
+		case "stale":
+			opts = append(opts, client.Stale(*maxStaleness))
+		default:
+			fmt.Printf("Unknown consistency: %s\n", *consistency)
+			os.Exit(1)
+		}
+		val, found, err := c.Get(key, opts...)
 		if err != nil {
 			fmt.Printf("Error getting key: %v\n", err)
 			os.Exit(1)
@@ -57,6 +81,17 @@ func main() {
 			fmt.Println(val)
 		}
 
+	case "del":
+		if len(args) != 2 {
+			fmt.Println("Usage: grass-cli del <key>")
+			os.Exit(1)
+		}
+		if err := c.Delete(args[1]); err != nil {
+			fmt.Printf("Error deleting key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK")
+
 	case "snapshot":
 		if err := c.TakeSnapshot(); err != nil {
 			fmt.Printf("Error taking snapshot: %v\n", err)
@@ -64,8 +99,96 @@ func main() {
 		}
 		fmt.Println("Snapshot created successfully")
 
+	case "status":
+		for _, peer := range peers {
+			st, err := c.Status(peer)
+			if err != nil {
+				fmt.Printf("%s: unreachable (%v)\n", peer, err)
+				continue
+			}
+			snapshot := "none"
+			if st.LastSnapshotTimeUnixMs != 0 {
+				at := time.UnixMilli(st.LastSnapshotTimeUnixMs).Format(time.RFC3339)
+				snapshot = fmt.Sprintf("index %d at %s", st.LastSnapshotIndex, at)
+			}
+			fmt.Printf("%s: %s %s term=%d leader=%s commit=%d applied=%d snapshot=%s members=%s\n",
+				peer, st.Id, st.State, st.Term, st.LeaderId, st.CommitIndex, st.LastApplied, snapshot, formatMembers(st.Members))
+		}
+
+	case "add-member":
+		if len(args) != 3 {
+			fmt.Println("Usage: grass-cli add-member <id> <addr>")
+			os.Exit(1)
+		}
+		members, err := c.AddMember(args[1], args[2])
+		if err != nil {
+			fmt.Printf("Error adding member: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK, members:", formatMembers(members))
+
+	case "add-learner":
+		if len(args) != 3 {
+			fmt.Println("Usage: grass-cli add-learner <id> <addr>")
+			os.Exit(1)
+		}
+		members, err := c.AddLearner(args[1], args[2])
+		if err != nil {
+			fmt.Printf("Error adding learner: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK, members:", formatMembers(members))
+
+	case "promote-member":
+		if len(args) != 2 {
+			fmt.Println("Usage: grass-cli promote-member <id>")
+			os.Exit(1)
+		}
+		members, err := c.PromoteMember(args[1])
+		if err != nil {
+			fmt.Printf("Error promoting member: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK, members:", formatMembers(members))
+
+	case "remove-member":
+		if len(args) != 2 {
+			fmt.Println("Usage: grass-cli remove-member <id>")
+			os.Exit(1)
+		}
+		members, err := c.RemoveMember(args[1])
+		if err != nil {
+			fmt.Printf("Error removing member: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK, members:", formatMembers(members))
+
+	case "transfer-leader":
+		if len(args) != 2 {
+			fmt.Println("Usage: grass-cli transfer-leader <id>")
+			os.Exit(1)
+		}
+		leader, err := c.TransferLeadership(args[1])
+		if err != nil {
+			fmt.Printf("Error transferring leadership: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("OK, leader:", leader)
+
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		os.Exit(1)
 	}
 }
+
+// formatMembers renders a cluster configuration as id=addr pairs.
+func formatMembers(members []*pb.Member) string {
+	parts := make([]string, len(members))
+	for i, m := range members {
+		parts[i] = m.Id + "=" + m.Addr
+		if m.Learner {
+			parts[i] += "(learner)"
+		}
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
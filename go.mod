@@ -7,6 +7,7 @@ toolchain go1.24.3
 require (
 	github.com/ranjan42/grassdb/proto v0.0.0
 	google.golang.org/grpc v1.78.0
+	google.golang.org/protobuf v1.36.11
 )
 
 require (
@@ -14,7 +15,6 @@ require (
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
-	google.golang.org/protobuf v1.36.11 // indirect
 )
 
 replace github.com/ranjan42/grassdb/proto => ./proto
@@ -2,6 +2,8 @@ package raft
 
 import (
 	"sync"
+
+	pb "github.com/ranjan42/grassdb/proto"
 )
 
 type LogEntry struct {
@@ -49,3 +51,46 @@ func (l *Log) LastTerm() int {
 	}
 	return l.entries[len(l.entries)-1].Term
 }
+
+// The RaftNode log uses 1-based indices. Entries up to lastIncludedIndex have
+// been compacted into a snapshot, so rn.log[0] holds index lastIncludedIndex+1.
+// All helpers below must be called with rn.mu held.
+
+// lastLogIndex returns the index of the last entry in the log.
+func (rn *RaftNode) lastLogIndex() int {
+	return rn.lastIncludedIndex + len(rn.log)
+}
+
+// lastLogTerm returns the term of the last entry in the log.
+func (rn *RaftNode) lastLogTerm() int {
+	return rn.termAt(rn.lastLogIndex())
+}
+
+// entryAt returns the entry at index, or nil if it is compacted or missing.
+func (rn *RaftNode) entryAt(index int) *pb.LogEntry {
+	pos := index - rn.lastIncludedIndex - 1
+	if pos < 0 || pos >= len(rn.log) {
+		return nil
+	}
+	return rn.log[pos]
+}
+
+// termAt returns the term of the entry at index, or -1 if it is unknown.
+func (rn *RaftNode) termAt(index int) int {
+	if index == rn.lastIncludedIndex {
+		return rn.lastIncludedTerm
+	}
+	if entry := rn.entryAt(index); entry != nil {
+		return int(entry.Term)
+	}
+	return -1
+}
+
+// entriesFrom returns a copy of the entries starting at index.
+func (rn *RaftNode) entriesFrom(index int) []*pb.LogEntry {
+	pos := index - rn.lastIncludedIndex - 1
+	if pos < 0 || pos >= len(rn.log) {
+		return nil
+	}
+	return append([]*pb.LogEntry(nil), rn.log[pos:]...)
+}
@@ -0,0 +1,291 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	pb "github.com/ranjan42/grassdb/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrConfigChangeInProgress is returned when a membership change is proposed
+	// before the previous one has committed.
+	ErrConfigChangeInProgress = errors.New("a configuration change is already in progress")
+	// ErrLearnerBehind is returned when promoting a learner that has not caught up with the leader.
+	ErrLearnerBehind = errors.New("learner has not caught up with the leader")
+)
+
+// learnerCatchUpLag is how many entries a learner may trail the leader's log
+// by and still be promoted: what a single AppendEntries can carry.
+const learnerCatchUpLag = maxAppendEntries
+
+// Membership changes add or remove one server at a time, so any majority of
+// the old configuration overlaps any majority of the new one. A configuration
+// takes effect on each node as soon as its entry is appended to the log,
+// without waiting for it to commit, and is rolled back if the entry is
+// truncated.
+//
+// Learners are members that receive the log but neither vote nor count toward
+// quorum, so adding one, or letting one fall behind, never affects write
+// latency or availability.
+
+// AddMember adds a node to the cluster as a voting member and returns once
+// the change is committed. The node should be started with Join set. Until it
+// catches up the cluster needs one more node to commit, so prefer AddLearner
+// followed by PromoteMember.
+func (rn *RaftNode) AddMember(ctx context.Context, id, addr string) error {
+	return rn.addMember(ctx, &pb.Member{Id: id, Addr: addr})
+}
+
+// AddLearner adds a node to the cluster as a learner and returns once the
+// change is committed. The node should be started with Join set.
+func (rn *RaftNode) AddLearner(ctx context.Context, id, addr string) error {
+	return rn.addMember(ctx, &pb.Member{Id: id, Addr: addr, Learner: true})
+}
+
+func (rn *RaftNode) addMember(ctx context.Context, member *pb.Member) error {
+	if member.Id == "" || member.Addr == "" {
+		return errors.New("member id and address are required")
+	}
+	return rn.proposeConfigChange(ctx, func(members []*pb.Member) ([]*pb.Member, error) {
+		for _, m := range members {
+			if m.Id == member.Id {
+				return nil, fmt.Errorf("%s is already a member", member.Id)
+			}
+		}
+		return append(members, member), nil
+	})
+}
+
+// PromoteMember makes a learner a voting member once its log is within
+// learnerCatchUpLag entries of the leader's, and returns once the change is
+// committed.
+func (rn *RaftNode) PromoteMember(ctx context.Context, id string) error {
+	return rn.proposeConfigChange(ctx, func(members []*pb.Member) ([]*pb.Member, error) {
+		for i, m := range members {
+			if m.Id != id {
+				continue
+			}
+			if !m.Learner {
+				return nil, fmt.Errorf("%s is already a voter", id)
+			}
+			if rn.matchIndex[id]+learnerCatchUpLag < rn.lastLogIndex() {
+				return nil, fmt.Errorf("%w: %s has %d of %d entries", ErrLearnerBehind, id, rn.matchIndex[id], rn.lastLogIndex())
+			}
+			members[i] = &pb.Member{Id: m.Id, Addr: m.Addr}
+			return members, nil
+		}
+		return nil, fmt.Errorf("%s is not a member", id)
+	})
+}
+
+// RemoveMember removes a node from the cluster and returns once the change is
+// committed. A leader that removes itself steps down at that point.
+func (rn *RaftNode) RemoveMember(ctx context.Context, id string) error {
+	return rn.proposeConfigChange(ctx, func(members []*pb.Member) ([]*pb.Member, error) {
+		kept := make([]*pb.Member, 0, len(members))
+		for _, m := range members {
+			if m.Id != id {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == len(members) {
+			return nil, fmt.Errorf("%s is not a member", id)
+		}
+		if countVoters(kept) == 0 {
+			return nil, errors.New("cannot remove the last voter")
+		}
+		return kept, nil
+	})
+}
+
+// Members returns the node's current cluster configuration.
+func (rn *RaftNode) Members() []*pb.Member {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	return rn.config.GetMembers()
+}
+
+// proposeConfigChange appends a configuration entry with the members change
+// returns and waits for it to be committed.
+func (rn *RaftNode) proposeConfigChange(ctx context.Context, change func([]*pb.Member) ([]*pb.Member, error)) error {
+	return rn.propose(ctx, func() (*pb.LogEntry, error) {
+		// Until the leader commits an entry in its term it cannot know
+		// whether a change from an earlier leader is still pending
+		if rn.configIndex > rn.commitIndex || rn.termAt(rn.commitIndex) != rn.currentTerm {
+			return nil, ErrConfigChangeInProgress
+		}
+		members, err := change(append([]*pb.Member(nil), rn.config.GetMembers()...))
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+		return &pb.LogEntry{Type: pb.EntryType_ENTRY_CONFIG, Config: &pb.ClusterConfig{Members: members}}, nil
+	})
+}
+
+// bootstrapConfig returns the configuration a node starts with before its log
+// or snapshot says otherwise: itself and the peers it was started with, or
+// nothing if it is joining an existing cluster.
+func bootstrapConfig(cfg Config) *pb.ClusterConfig {
+	if cfg.Join {
+		return &pb.ClusterConfig{}
+	}
+	members := []*pb.Member{{Id: cfg.ID, Addr: cfg.Addr}}
+	for id, addr := range cfg.Peers {
+		members = append(members, &pb.Member{Id: id, Addr: addr})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+	return &pb.ClusterConfig{Members: members}
+}
+
+// configAt returns the configuration in effect at index and the index of the
+// entry that set it: the latest configuration entry up to index, or else the
+// snapshot's. Caller must hold rn.mu.
+func (rn *RaftNode) configAt(index int) (*pb.ClusterConfig, int) {
+	for i := min(index, rn.lastLogIndex()); i > rn.lastIncludedIndex; i-- {
+		if entry := rn.entryAt(i); entry.Type == pb.EntryType_ENTRY_CONFIG {
+			return entry.Config, i
+		}
+	}
+	return rn.snapshotConfig, rn.lastIncludedIndex
+}
+
+// refreshConfig adopts the latest configuration in the log, after entries were
+// appended, truncated or compacted. Caller must hold rn.mu.
+func (rn *RaftNode) refreshConfig() {
+	rn.setConfig(rn.configAt(rn.lastLogIndex()))
+}
+
+// setConfig makes cfg, set by the entry at index, the current configuration.
+// Caller must hold rn.mu.
+func (rn *RaftNode) setConfig(cfg *pb.ClusterConfig, index int) {
+	changed := !proto.Equal(cfg, rn.config)
+	rn.config, rn.configIndex = cfg, index
+	if !changed {
+		return
+	}
+
+	var peers, learners []string
+	peerAddrs := make(map[string]string, len(cfg.GetMembers()))
+	for _, m := range cfg.GetMembers() {
+		if m.Id == rn.id {
+			continue
+		}
+		if m.Learner {
+			learners = append(learners, m.Id)
+		} else {
+			peers = append(peers, m.Id)
+		}
+		peerAddrs[m.Id] = m.Addr
+	}
+	sort.Strings(peers)
+	sort.Strings(learners)
+
+	// Forget peers that left, or moved, and start tracking new ones
+	for p, addr := range rn.peerAddrs {
+		if peerAddrs[p] != addr {
+			delete(rn.nextIndex, p)
+			delete(rn.matchIndex, p)
+			delete(rn.lastAck, p)
+		}
+	}
+	for p := range peerAddrs {
+		if _, ok := rn.nextIndex[p]; !ok && rn.state == Leader {
+			rn.nextIndex[p] = rn.lastLogIndex() + 1
+			rn.matchIndex[p] = 0
+		}
+	}
+	rn.peers, rn.learners, rn.peerAddrs = peers, learners, peerAddrs
+
+	log.Printf("[%s] Cluster configuration at index %d: %s", rn.id, index, formatMembers(cfg.GetMembers()))
+}
+
+// isMember reports whether id is in the current configuration. Caller must hold rn.mu.
+func (rn *RaftNode) isMember(id string) bool {
+	for _, m := range rn.config.GetMembers() {
+		if m.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isVoter reports whether id is a voting member in the current configuration.
+// Caller must hold rn.mu.
+func (rn *RaftNode) isVoter(id string) bool {
+	for _, m := range rn.config.GetMembers() {
+		if m.Id == id {
+			return !m.Learner
+		}
+	}
+	return false
+}
+
+// isLearner reports whether id is a learner in the current configuration.
+// Caller must hold rn.mu.
+func (rn *RaftNode) isLearner(id string) bool {
+	return rn.isMember(id) && !rn.isVoter(id)
+}
+
+// voters returns the number of voting members. Caller must hold rn.mu.
+func (rn *RaftNode) voters() int {
+	return countVoters(rn.config.GetMembers())
+}
+
+// countVoters returns how many of members vote.
+func countVoters(members []*pb.Member) int {
+	n := 0
+	for _, m := range members {
+		if !m.Learner {
+			n++
+		}
+	}
+	return n
+}
+
+// hasConfigEntry reports whether any of entries changes the configuration.
+func hasConfigEntry(entries []*pb.LogEntry) bool {
+	for _, e := range entries {
+		if e.Type == pb.EntryType_ENTRY_CONFIG {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeConfig encodes a configuration for snapshot metadata.
+func encodeConfig(cfg *pb.ClusterConfig) []byte {
+	data, _ := proto.Marshal(cfg) // Marshalling a valid message cannot fail
+	return data
+}
+
+// decodeConfig decodes a configuration from snapshot metadata. Snapshots taken
+// before configurations were recorded yield nil.
+func decodeConfig(data []byte) (*pb.ClusterConfig, error) {
+	if data == nil {
+		return nil, nil
+	}
+	cfg := &pb.ClusterConfig{}
+	if err := proto.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("snapshot config: %w", err)
+	}
+	return cfg, nil
+}
+
+// formatMembers renders members as id=addr pairs for logs.
+func formatMembers(members []*pb.Member) string {
+	parts := make([]string, len(members))
+	for i, m := range members {
+		parts[i] = m.Id + "=" + m.Addr
+		if m.Learner {
+			parts[i] += "(learner)"
+		}
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
@@ -0,0 +1,189 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// waitFor fails the test unless cond holds within five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// hasMember reports whether rn's configuration holds id, and whether as a learner.
+func hasMember(rn *RaftNode, id string) (member, learner bool) {
+	for _, m := range rn.Members() {
+		if m.Id == id {
+			return true, m.Learner
+		}
+	}
+	return false, false
+}
+
+func TestRemoveLeader(t *testing.T) {
+	_, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leader.RemoveMember(ctx, leader.id); err != nil {
+		t.Fatalf("RemoveMember(%s): %v", leader.id, err)
+	}
+	waitFor(t, "the removed leader to step down", func() bool { return !leader.IsLeader() })
+
+	// The other two carry on as a cluster of their own
+	var rest []*RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			rest = append(rest, rn)
+		}
+	}
+	next := waitForLeader(t, rest)
+	for _, rn := range rest {
+		if member, _ := hasMember(rn, leader.id); member {
+			t.Fatalf("%s still counts %s as a member", rn.id, leader.id)
+		}
+	}
+	if err := next.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("Propose after removing the leader: %v", err)
+	}
+}
+
+func TestConfigChangeWhileOneIsUncommitted(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+
+	// Cut off from the followers, the leader appends a change it cannot commit
+	net.Partition([]string{leader.id})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := leader.AddLearner(ctx, "n4", "n4"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AddLearner without a quorum = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if member, _ := hasMember(leader, "n4"); !member {
+		t.Fatalf("uncommitted change has not taken effect on the leader")
+	}
+
+	// Until it commits, a second change is refused outright
+	err := leader.RemoveMember(context.Background(), nodes[0].id)
+	if !errors.Is(err, ErrConfigChangeInProgress) {
+		t.Fatalf("second change = %v, want %v", err, ErrConfigChangeInProgress)
+	}
+
+	// Once the partition heals the first change commits, and changes are
+	// accepted again
+	net.Heal()
+	leader = waitForLeader(t, nodes)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waitFor(t, "a change to be accepted", func() bool {
+		err := leader.RemoveMember(ctx, "n4")
+		return err == nil || !errors.Is(err, ErrConfigChangeInProgress)
+	})
+	if member, _ := hasMember(leader, "n4"); member {
+		t.Fatalf("n4 is still a member after being removed")
+	}
+}
+
+// joinMemCluster starts a node with Join set on net, for adding to a cluster.
+func joinMemCluster(t *testing.T, net *MemNetwork, id string) *RaftNode {
+	rn := newMemNode(t, net, Config{ID: id, Addr: id, Join: true})
+	rn.start()
+	t.Cleanup(rn.Stop)
+	return rn
+}
+
+func TestAddLearnerThenPromote(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+			t.Fatalf("Propose: %v", err)
+		}
+	}
+
+	n4 := joinMemCluster(t, net, "n4")
+	if err := leader.AddLearner(ctx, "n4", "n4"); err != nil {
+		t.Fatalf("AddLearner: %v", err)
+	}
+	if _, learner := hasMember(leader, "n4"); !learner {
+		t.Fatalf("n4 was not added as a learner")
+	}
+	want := leader.Status().CommitIndex
+	waitFor(t, "the learner to catch up", func() bool { return n4.Status().CommitIndex >= want })
+
+	if err := leader.PromoteMember(ctx, "n4"); err != nil {
+		t.Fatalf("PromoteMember: %v", err)
+	}
+	waitFor(t, "n4 to see itself promoted", func() bool {
+		member, learner := hasMember(n4, "n4")
+		return member && !learner
+	})
+
+	// With one of the original followers gone, n4's vote is needed to commit
+	var follower *RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			follower = rn
+			break
+		}
+	}
+	net.Partition([]string{follower.id})
+	if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v2"}); err != nil {
+		t.Fatalf("Propose with n4 voting: %v", err)
+	}
+	net.Heal()
+}
+
+func TestPromoteLearnerBehind(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	n4 := joinMemCluster(t, net, "n4")
+	if err := leader.AddLearner(ctx, "n4", "n4"); err != nil {
+		t.Fatalf("AddLearner: %v", err)
+	}
+
+	// The learner misses more entries than one AppendEntries can carry
+	net.Partition([]string{n4.id})
+	errs := make(chan error)
+	for i := 0; i < learnerCatchUpLag+10; i++ {
+		go func() {
+			errs <- leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"})
+		}()
+	}
+	for i := 0; i < learnerCatchUpLag+10; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Propose: %v", err)
+		}
+	}
+	if err := leader.PromoteMember(ctx, "n4"); !errors.Is(err, ErrLearnerBehind) {
+		t.Fatalf("PromoteMember of a lagging learner = %v, want %v", err, ErrLearnerBehind)
+	}
+	if _, learner := hasMember(leader, "n4"); !learner {
+		t.Fatalf("lagging learner was promoted")
+	}
+
+	// Once it catches up it can be promoted
+	net.Heal()
+	want := leader.Status().CommitIndex
+	waitFor(t, "the learner to catch up", func() bool { return n4.Status().CommitIndex >= want })
+	if err := leader.PromoteMember(ctx, "n4"); err != nil {
+		t.Fatalf("PromoteMember after catching up: %v", err)
+	}
+}
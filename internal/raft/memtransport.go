@@ -0,0 +1,302 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnreachable is returned by a MemTransport for calls to a node that is
+// partitioned away, stopped or not serving.
+var ErrUnreachable = errors.New("node unreachable")
+
+// MemNetwork connects nodes in one process, so a whole cluster can run inside
+// a test. Every request and reply travels over channels, and the network can
+// be told to drop, delay or duplicate them at random, or to partition nodes.
+// A dropped request or reply is simply never delivered, so the caller times
+// out as it would on a real network; calls across a partition fail at once.
+type MemNetwork struct {
+	mu        sync.Mutex
+	endpoints map[string]*MemTransport
+	group     map[string]int // Partition each address is in; unlisted addresses are in group 0
+	rand      *rand.Rand
+	dropRate  float64
+	dupRate   float64
+	minDelay  time.Duration
+	maxDelay  time.Duration
+}
+
+// NewMemNetwork returns a fault-free network whose random choices are seeded
+// with seed.
+func NewMemNetwork(seed int64) *MemNetwork {
+	return &MemNetwork{
+		endpoints: make(map[string]*MemTransport),
+		group:     make(map[string]int),
+		rand:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Transport returns the endpoint for the node at addr, which should be passed
+// as Config.Transport and then given the node with Serve.
+func (n *MemNetwork) Transport(addr string) *MemTransport {
+	t := &MemTransport{
+		net:    n,
+		addr:   addr,
+		inbox:  make(chan memMessage, 256),
+		closed: make(chan struct{}),
+	}
+	n.mu.Lock()
+	n.endpoints[addr] = t
+	n.mu.Unlock()
+	go t.dispatch()
+	return t
+}
+
+// Partition splits the network so that only nodes in the same group can
+// reach each other. Nodes not listed form one more group together.
+func (n *MemNetwork) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.group = make(map[string]int)
+	for i, g := range groups {
+		for _, addr := range g {
+			n.group[addr] = i + 1
+		}
+	}
+}
+
+// Heal removes any partition.
+func (n *MemNetwork) Heal() {
+	n.Partition()
+}
+
+// SetDropRate makes the network lose each request and reply with probability p.
+func (n *MemNetwork) SetDropRate(p float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dropRate = p
+}
+
+// SetDuplicateRate makes the network deliver each request twice with probability p.
+func (n *MemNetwork) SetDuplicateRate(p float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dupRate = p
+}
+
+// SetDelay delays each request and reply by a random duration in [min, max],
+// which also reorders them.
+func (n *MemNetwork) SetDelay(min, max time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.minDelay, n.maxDelay = min, max
+}
+
+// route returns the endpoint a message from one address to another goes to,
+// or ErrUnreachable if the two can't talk.
+func (n *MemNetwork) route(from, to string) (*MemTransport, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	dst, ok := n.endpoints[to]
+	src := n.endpoints[from]
+	if !ok || src == nil || dst.isClosed() || src.isClosed() || n.group[from] != n.group[to] {
+		return nil, ErrUnreachable
+	}
+	return dst, nil
+}
+
+// fate decides how many copies of a message arrive, and after how long each.
+func (n *MemNetwork) fate(canDuplicate bool) []time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	copies := 1
+	if canDuplicate && n.rand.Float64() < n.dupRate {
+		copies = 2
+	}
+	var delays []time.Duration
+	for i := 0; i < copies; i++ {
+		if n.rand.Float64() < n.dropRate {
+			continue
+		}
+		d := n.minDelay
+		if n.maxDelay > n.minDelay {
+			d += time.Duration(n.rand.Int63n(int64(n.maxDelay - n.minDelay + 1)))
+		}
+		delays = append(delays, d)
+	}
+	return delays
+}
+
+// after runs fn once d has passed, or right away if d is zero.
+func after(d time.Duration, fn func()) {
+	if d == 0 {
+		fn()
+		return
+	}
+	time.AfterFunc(d, fn)
+}
+
+// memMessage is a request in flight to a MemTransport.
+type memMessage struct {
+	ctx   context.Context
+	from  string
+	call  func(ctx context.Context, h Handler) (any, error)
+	reply chan memReply
+}
+
+type memReply struct {
+	resp any
+	err  error
+}
+
+// MemTransport is one node's endpoint on a MemNetwork. It implements
+// Transport for the node's outgoing RPCs and delivers incoming ones to the
+// Handler given to Serve.
+type MemTransport struct {
+	net       *MemNetwork
+	addr      string
+	inbox     chan memMessage
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu      sync.Mutex
+	handler Handler
+}
+
+// Serve starts delivering incoming RPCs to h. Until then they fail.
+func (t *MemTransport) Serve(h Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = h
+}
+
+// Close takes the endpoint off the network. Calls to and from it fail.
+func (t *MemTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
+
+func (t *MemTransport) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatch hands each incoming request to the handler.
+func (t *MemTransport) dispatch() {
+	for {
+		select {
+		case m := <-t.inbox:
+			go t.deliver(m)
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// deliver runs a request against the handler and sends the reply back,
+// subject to the network's faults.
+func (t *MemTransport) deliver(m memMessage) {
+	if _, err := t.net.route(m.from, t.addr); err != nil {
+		return // Partitioned while in flight
+	}
+	t.mu.Lock()
+	h := t.handler
+	t.mu.Unlock()
+
+	var r memReply
+	if h == nil {
+		r.err = ErrUnreachable
+	} else {
+		r.resp, r.err = m.call(m.ctx, h)
+	}
+	for _, d := range t.net.fate(false) {
+		after(d, func() { m.reply <- r })
+	}
+}
+
+// memCall sends a request from t to the node at addr and waits for a reply.
+func memCall[Resp any](ctx context.Context, t *MemTransport, addr string, call func(ctx context.Context, h Handler) (Resp, error)) (Resp, error) {
+	var zero Resp
+	dst, err := t.net.route(t.addr, addr)
+	if err != nil {
+		return zero, err
+	}
+
+	m := memMessage{
+		ctx:   ctx,
+		from:  t.addr,
+		call:  func(ctx context.Context, h Handler) (any, error) { return call(ctx, h) },
+		reply: make(chan memReply, 2), // Room for the replies to both copies of a duplicate
+	}
+	for _, d := range t.net.fate(true) {
+		after(d, func() {
+			select {
+			case dst.inbox <- m:
+			case <-dst.closed:
+			}
+		})
+	}
+
+	select {
+	case r := <-m.reply:
+		if r.err != nil {
+			return zero, r.err
+		}
+		return r.resp.(Resp), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-t.closed:
+		return zero, ErrUnreachable
+	}
+}
+
+// clone copies a request so sender and receiver never share it, as they
+// wouldn't over a real network.
+func clone[M proto.Message](m M) M {
+	return proto.Clone(m).(M)
+}
+
+func (t *MemTransport) RequestVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.RequestVoteResponse, error) {
+		return h.RequestVote(ctx, clone(args))
+	})
+}
+
+func (t *MemTransport) PreVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.RequestVoteResponse, error) {
+		return h.PreVote(ctx, clone(args))
+	})
+}
+
+func (t *MemTransport) AppendEntries(ctx context.Context, addr string, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.AppendEntriesResponse, error) {
+		return h.AppendEntries(ctx, clone(args))
+	})
+}
+
+func (t *MemTransport) InstallSnapshot(ctx context.Context, addr string, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.InstallSnapshotResponse, error) {
+		return h.InstallSnapshot(ctx, clone(args))
+	})
+}
+
+func (t *MemTransport) TimeoutNow(ctx context.Context, addr string, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.TimeoutNowResponse, error) {
+		return h.TimeoutNow(ctx, clone(args))
+	})
+}
+
+func (t *MemTransport) ReadIndex(ctx context.Context, addr string, args *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	return memCall(ctx, t, addr, func(ctx context.Context, h Handler) (*pb.ReadIndexResponse, error) {
+		return h.ReadIndex(ctx, clone(args))
+	})
+}
@@ -1,10 +1,17 @@
 package raft
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"grassdb/internal/storage"
@@ -18,8 +25,63 @@ const (
 	Follower  State = "Follower"
 	Candidate State = "Candidate"
 	Leader    State = "Leader"
+	// Learner is reported by Status for followers that are learners in the
+	// configuration, which never become candidates.
+	Learner State = "Learner"
 )
 
+var (
+	// ErrNotLeader is returned when a proposal is made to a node that is not the leader.
+	ErrNotLeader = errors.New("not leader")
+	// ErrProposalDropped is returned when a proposed entry was overwritten by a new leader
+	// before it could be committed.
+	ErrProposalDropped = errors.New("proposal dropped after leadership change")
+	// ErrStopped is returned for RPCs and proposals made to a node that has been stopped.
+	ErrStopped = errors.New("raft node stopped")
+	// ErrNoDataDir is returned by Snapshot on a node without a data directory,
+	// which has nowhere to keep the snapshot.
+	ErrNoDataDir = errors.New("no data directory to keep snapshots in")
+)
+
+// ApplyMsg carries a committed log entry to the state machine, or a snapshot
+// from the leader that replaces the state machine's state up to Index. The
+// consumer must call Done once the entry has been applied.
+type ApplyMsg struct {
+	Index int
+	Term  int
+	Entry *pb.LogEntry
+	// Snapshot is set, and Entry nil, when the message carries a snapshot. It
+	// reads the snapshot in the storage snapshot format and is only valid
+	// until Done is called.
+	Snapshot io.Reader
+
+	done chan struct{}
+}
+
+// Done signals that the entry has been applied to the state machine.
+func (m ApplyMsg) Done() {
+	close(m.done)
+}
+
+// proposal tracks a client entry waiting to be committed and applied.
+type proposal struct {
+	term int
+	done chan error
+}
+
+// proposeMsg asks the event loop to append the entry build returns to the log.
+type proposeMsg struct {
+	build func() (*pb.LogEntry, error)
+	reply chan proposed
+}
+
+// proposed is the event loop's answer to a proposeMsg.
+type proposed struct {
+	index int
+	p     *proposal
+	err   error
+}
+
 type RaftNode struct {
 	mu          sync.Mutex
 	id          string
@@ -36,157 +98,522 @@ type RaftNode struct {
 	nextIndex  map[string]int
 	matchIndex map[string]int
 
-	peers              []string
-	electionTimer      *time.Timer
-	heartbeatTimer     *time.Timer
-	leaderTimeoutTimer *time.Timer // Timer to detect leader failure
-	applyCh            chan string // simplified state machine
-	peerClients        map[string]pb.DatabaseClient
+	addr              string               // Our own gRPC address, advertised when we lead
+	peers             []string             // IDs of the other voting members, sorted
+	learners          []string             // IDs of the other members that don't vote, sorted
+	peerAddrs         map[string]string    // Peer ID -> gRPC address
+	config            *pb.ClusterConfig    // Latest configuration in the log, in effect
+	configIndex       int                  // Index of the entry that set config
+	snapshotConfig    *pb.ClusterConfig    // Configuration at lastIncludedIndex
+	leaderID          string               // Leader of currentTerm, if known
+	lastLeaderContact time.Time            // When we last heard from a valid leader
+	lastAck           map[string]time.Time // Leader only: send time of each peer's latest reply in our term
+	leaderSince       time.Time            // Leader only: when we won the election
+	electionTimer     Timer                // Armed unless we are leader
+	heartbeatTimer    Timer                // Armed only while we are leader
+	election          *election            // Pre-vote or election we are running, if any
+	applyCh           chan ApplyMsg
+	commitCh          chan struct{} // signals the applier that commitIndex moved
+	appliedCh         chan struct{} // closed and replaced whenever lastApplied moves
+	proposals         map[int]*proposal
+	transport         Transport
+	transferTarget    string                 // Leader only: node we are handing leadership to
+	transferCh        chan struct{}          // closed and replaced, during a transfer, when the target's match index or our leader moves
+	replicators       map[string]*replicator // Replication goroutine running for each peer
+	clock             Clock
+	rand              *rand.Rand   // Picks election timeouts; guarded by mu
+	spawn             func(func()) // Starts background work such as replication
+
+	// Event loop inputs
+	rpcCh     chan func()
+	proposeCh chan proposeMsg
+	voteCh    chan voteReply
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup // The event loop and the applier
 
 	// Snapshot state
 	lastIncludedIndex int
 	lastIncludedTerm  int
+	lastSnapshotTime  time.Time         // when the current snapshot was taken or installed
+	snapshotMu        sync.Mutex        // serializes Snapshot calls
+	sendingSnapshot   map[string]bool   // peers the leader is streaming its snapshot to
+	recvSnapshot      *snapshotReceiver // snapshot being received from the leader
+	pendingSnapshot   *pendingSnapshot  // installed snapshot not yet handed to the state machine
+
+	// Durable state; stable is nil for in-memory nodes
+	dataDir   string
+	stable    *StableStore
+	persisted PersistentState
+
+	// Read settings and counters
+	leaseReads     bool
+	leaseDrift     time.Duration
+	leaseReadCount atomic.Uint64
+	readIndexCount atomic.Uint64
 }
 
-func NewRaftNode(id string, peers []string, applyCh chan string) *RaftNode {
+// Config holds the settings for a RaftNode.
+type Config struct {
+	ID   string
+	Addr string // gRPC address other nodes and clients use to reach us
+	// Peers maps every other node's ID to its gRPC address. It is only the
+	// initial configuration; membership changes in the log supersede it.
+	Peers map[string]string
+	// Join starts the node with no configuration, to be added to an existing
+	// cluster with AddMember. It will not stand for election until then.
+	Join bool
+	// DataDir is where Raft hard state and the log are persisted.
+	// An empty DataDir keeps everything in memory.
+	DataDir string
+	// LeaseReads lets the leader serve linearizable reads from local state,
+	// without a ReadIndex heartbeat round, while its lease holds.
+	LeaseReads bool
+	// LeaseDriftBound shortens the lease to absorb clock drift between nodes.
+	// Defaults to DefaultLeaseDriftBound.
+	LeaseDriftBound time.Duration
+	// Transport carries RPCs to the other nodes. Defaults to a GRPCTransport.
+	// The node closes it when stopped.
+	Transport Transport
+	// Clock supplies the time and the election and heartbeat timers.
+	// Defaults to the system clock.
+	Clock Clock
+	// Rand randomizes election timeouts. Defaults to a source seeded from
+	// the time. It need not be safe for concurrent use.
+	Rand *rand.Rand
+}
+
+// DefaultLeaseDriftBound is the lease drift bound used when none is configured.
+const DefaultLeaseDriftBound = 100 * time.Millisecond
+
+func NewRaftNode(cfg Config, applyCh chan ApplyMsg) (*RaftNode, error) {
+	rn, err := openRaftNode(cfg, applyCh)
+	if err != nil {
+		return nil, err
+	}
+	rn.start()
+	return rn, nil
+}
+
+// openRaftNode builds a node and reloads its state from cfg.DataDir, without
+// starting its event loop.
+func openRaftNode(cfg Config, applyCh chan ApplyMsg) (*RaftNode, error) {
+	rn := newRaftNode(cfg, applyCh)
+	rn.dataDir = cfg.DataDir
+	if cfg.DataDir != "" {
+		if err := rn.restore(filepath.Join(cfg.DataDir, fmt.Sprintf("distdb_%s_raft", cfg.ID))); err != nil {
+			return nil, fmt.Errorf("failed to load raft state: %w", err)
+		}
+	}
+	// We may have acknowledged a leader just before restarting, so hold off
+	// voting as if we had just heard from it; leases rely on this.
+	rn.lastLeaderContact = rn.clock.Now()
+	return rn, nil
+}
+
+// newRaftNode builds an in-memory node without starting its event loop.
+// cfg.DataDir is ignored.
+func newRaftNode(cfg Config, applyCh chan ApplyMsg) *RaftNode {
+	leaseDrift := cfg.LeaseDriftBound
+	if leaseDrift == 0 {
+		leaseDrift = DefaultLeaseDriftBound
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	rnd := cfg.Rand
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
 	rn := &RaftNode{
-		id:                 id,
-		peers:              peers,
-		state:              Follower,
-		applyCh:            applyCh,
-		electionTimer:      time.NewTimer(randomElectionTimeout()),
-		heartbeatTimer:     time.NewTimer(100 * time.Millisecond),
-		leaderTimeoutTimer: time.NewTimer(randomLeaderTimeout()),
-		log:                make([]*pb.LogEntry, 0),
-		nextIndex:          make(map[string]int),
-		matchIndex:         make(map[string]int),
-		peerClients:        make(map[string]pb.DatabaseClient),
+		id:              cfg.ID,
+		leaseReads:      cfg.LeaseReads,
+		leaseDrift:      leaseDrift,
+		addr:            cfg.Addr,
+		snapshotConfig:  bootstrapConfig(cfg),
+		state:           Follower,
+		applyCh:         applyCh,
+		commitCh:        make(chan struct{}, 1),
+		appliedCh:       make(chan struct{}),
+		transferCh:      make(chan struct{}),
+		proposals:       make(map[int]*proposal),
+		clock:           clock,
+		rand:            rnd,
+		spawn:           func(fn func()) { go fn() },
+		log:             make([]*pb.LogEntry, 0),
+		nextIndex:       make(map[string]int),
+		matchIndex:      make(map[string]int),
+		lastAck:         make(map[string]time.Time),
+		sendingSnapshot: make(map[string]bool),
+		replicators:     make(map[string]*replicator),
+		rpcCh:           make(chan func()),
+		proposeCh:       make(chan proposeMsg),
+		voteCh:          make(chan voteReply),
+		stopCh:          make(chan struct{}),
 	}
-	go rn.run()
+	rn.electionTimer = clock.NewTimer(rn.randomElectionTimeout())
+	rn.heartbeatTimer = clock.NewTimer(heartbeatInterval)
+	rn.heartbeatTimer.Stop()
+	rn.transport = cfg.Transport
+	if rn.transport == nil {
+		rn.transport = NewGRPCTransport()
+	}
+	rn.refreshConfig()
 	return rn
 }
 
-func (rn *RaftNode) run() {
-	for {
-		switch rn.state {
-		case Follower:
-			rn.runFollower()
-		case Candidate:
-			rn.runCandidate()
-		case Leader:
-			rn.runLeader()
-		}
+// restore opens the stable store in dir and reloads the hard state and log from it.
+func (rn *RaftNode) restore(dir string) error {
+	stable, err := NewStableStore(dir)
+	if err != nil {
+		return err
 	}
+	st, err := stable.LoadState()
+	if err != nil {
+		return err
+	}
+	prevIndex, prevTerm, entries, err := stable.LoadLog()
+	if err != nil {
+		return err
+	}
+
+	rn.stable = stable
+	rn.persisted = st
+	rn.currentTerm = st.CurrentTerm
+	rn.votedFor = st.VotedFor
+	rn.lastIncludedIndex = prevIndex
+	rn.lastIncludedTerm = prevTerm
+	rn.log = append(rn.log, entries...)
+
+	// The state machine starts from the snapshot, so everything it covers
+	// is already committed and applied. A crash while taking the snapshot
+	// can leave the log not yet trimmed to match it.
+	meta, err := storage.ReadSnapshotMetadata(rn.snapshotPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("load snapshot: %w", err)
+	}
+	snapConfig, err := decodeConfig(meta.Config)
+	if err != nil {
+		return err
+	}
+	if snapConfig == nil {
+		snapConfig = rn.snapshotConfig
+	}
+	if meta.LastIncludedIndex > rn.lastIncludedIndex {
+		rn.compactLog(meta, snapConfig)
+	} else {
+		rn.snapshotConfig = snapConfig
+	}
+	rn.refreshConfig()
+	if info, err := os.Stat(rn.snapshotPath()); err == nil {
+		rn.lastSnapshotTime = info.ModTime()
+	}
+	rn.commitIndex = rn.lastIncludedIndex
+	rn.lastApplied = rn.lastIncludedIndex
+
+	log.Printf("[%s] Restored term %d and %d log entries after index %d", rn.id, rn.currentTerm, len(rn.log), rn.lastIncludedIndex)
+	return nil
 }
 
-func (rn *RaftNode) runFollower() {
-	rn.resetElectionTimer()
-	rn.resetLeaderTimeoutTimer()
+// persistState fsyncs currentTerm and votedFor if they changed since the last
+// write. It must be called before replying to any RPC that changed them.
+// Caller must hold rn.mu.
+func (rn *RaftNode) persistState() {
+	st := PersistentState{CurrentTerm: rn.currentTerm, VotedFor: rn.votedFor}
+	if rn.stable == nil || st == rn.persisted {
+		return
+	}
+	if err := rn.stable.SaveState(st); err != nil {
+		// A node that cannot record its vote must not keep participating
+		log.Fatalf("[%s] Failed to persist raft state: %v", rn.id, err)
+	}
+	rn.persisted = st
+}
+
+// persistEntries fsyncs entries written to the log at index, replacing anything
+// that followed. Caller must hold rn.mu.
+func (rn *RaftNode) persistEntries(index int, entries []*pb.LogEntry) {
+	if rn.stable == nil {
+		return
+	}
+	if err := rn.stable.StoreEntries(index, entries); err != nil {
+		log.Fatalf("[%s] Failed to persist raft log: %v", rn.id, err)
+	}
+}
+
+// becomeFollower adopts a newer term seen in an RPC and steps down.
+// Caller must hold rn.mu.
+func (rn *RaftNode) becomeFollower(term int) {
+	rn.currentTerm = term
+	rn.votedFor = ""
+	rn.stepDown()
+	rn.persistState()
+}
+
+// The node is driven by a single event loop, run. Incoming RPCs and proposals
+// are handed to it over channels and handled one at a time, along with vote
+// replies and the election and heartbeat timers, which are only armed while
+// they matter, so a quiet node does no work. rn.mu still guards the state,
+// since replication, reads and status queries look at it from other
+// goroutines.
+
+// start launches the event loop and the applier.
+func (rn *RaftNode) start() {
+	rn.wg.Add(2)
+	go rn.run()
+	go rn.runApplier()
+}
+
+// Stop shuts the node down: it stops the event loop and the applier, waits
+// for them to exit, and closes the transport and stable store. RPCs
+// and proposals then fail with ErrStopped. Stop may be called more than once.
+func (rn *RaftNode) Stop() {
+	rn.stopOnce.Do(func() { close(rn.stopCh) })
+	rn.wg.Wait()
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.electionTimer.Stop()
+	rn.heartbeatTimer.Stop()
+	rn.state = Follower
+	rn.leaderID = ""
+	rn.notifyTransfer()
+	rn.transport.Close()
+	if rn.stable != nil {
+		rn.stable.Close()
+		rn.stable = nil // Replies still in flight must not touch it
+	}
+}
+
+func (rn *RaftNode) run() {
+	defer rn.wg.Done()
 	for {
 		select {
-		case <-rn.electionTimer.C:
-			rn.mu.Lock()
-			rn.state = Candidate
-			log.Printf("[%s] Election timeout, becoming Candidate", rn.id)
-			rn.mu.Unlock()
+		case <-rn.stopCh:
 			return
-		case <-rn.leaderTimeoutTimer.C:
-			rn.mu.Lock()
-			rn.state = Candidate
-			rn.mu.Unlock()
-			return
-		default:
-			rn.mu.Lock()
-			if rn.state != Follower {
-				rn.mu.Unlock()
-				return
-			}
-			rn.mu.Unlock()
-			time.Sleep(10 * time.Millisecond) // IDLE wait
+		case handle := <-rn.rpcCh:
+			handle()
+		case m := <-rn.proposeCh:
+			rn.appendProposal(m)
+		case v := <-rn.voteCh:
+			rn.handleVote(v)
+		case <-rn.electionTimer.C():
+			rn.electionTimeout()
+		case <-rn.heartbeatTimer.C():
+			rn.heartbeat()
 		}
 	}
 }
 
-func (rn *RaftNode) runCandidate() {
+// handle runs fn on the event loop and waits for it to finish.
+func (rn *RaftNode) handle(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	select {
+	case rn.rpcCh <- func() { fn(); close(done) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rn.stopCh:
+		return ErrStopped
+	}
+	<-done
+	return nil
+}
+
+// election tracks the votes for a pre-vote or an election we started.
+type election struct {
+	term    int  // Term we are campaigning in
+	pre     bool // Only a pre-vote; currentTerm is still term-1
+	needed  int  // Votes that make a majority of the voters, counting ours
+	granted map[string]bool
+}
+
+// voteReply is a peer's answer to one of our RequestVote or PreVote RPCs.
+type voteReply struct {
+	peer string
+	term int
+	pre  bool
+	resp *pb.RequestVoteResponse
+}
+
+// electionTimeout starts a pre-vote when we have not heard from a leader for
+// an election timeout, or our last election did not finish in time.
+// Runs on the event loop.
+func (rn *RaftNode) electionTimeout() {
 	rn.mu.Lock()
-	rn.currentTerm++
-	rn.votedFor = rn.id
+	defer rn.mu.Unlock()
+	if rn.state == Leader {
+		return
+	}
 	rn.resetElectionTimer()
-	term := rn.currentTerm
-	id := rn.id
-	rn.mu.Unlock()
+	if !rn.isVoter(rn.id) {
+		// Not a voter (yet), or removed since, so never stand for election
+		rn.state = Follower
+		rn.election = nil
+		return
+	}
+	log.Printf("[%s] Election timeout, starting pre-vote for term %d", rn.id, rn.currentTerm+1)
+	rn.campaign(true, false)
+}
 
-	// Send RequestVote to all peers
-	votes := 1 // Vote for self
-	voteCh := make(chan bool, len(rn.peers))
+// campaign asks the voters to elect us in the next term. A pre-vote only
+// asks whether they would, without changing our term or theirs. A node that
+// cannot win, such as one cut off by a partition, so never inflates its term
+// and forces a healthy leader to step down when it reconnects. Only once a
+// majority says yes do we run the real election. transfer marks an election
+// the leader asked us to hold, which voters grant despite having just heard
+// from it. Caller must hold rn.mu.
+func (rn *RaftNode) campaign(pre, transfer bool) {
+	term := rn.currentTerm + 1
+	if !pre {
+		rn.state = Candidate
+		rn.currentTerm = term
+		rn.votedFor = rn.id
+		rn.leaderID = ""
+		rn.persistState()
+		rn.resetElectionTimer()
+		log.Printf("[%s] Starting election for term %d", rn.id, term)
+	}
+	args := rn.requestVoteArgs()
+	args.Term = int64(term)
+	args.LeadershipTransfer = transfer
+	// Majority of the configuration we campaign in, ourselves included
+	rn.election = &election{term: term, pre: pre, needed: (len(rn.peers)+1)/2 + 1, granted: map[string]bool{rn.id: true}}
 
 	for _, peer := range rn.peers {
-		go func(p string) {
-			args := &pb.RequestVoteRequest{
-				Term:        int64(term),
-				CandidateId: id,
-				// LastLogIndex: ...
-				// LastLogTerm: ...
+		rn.spawn(func() {
+			send := rn.sendRequestVote
+			if pre {
+				send = rn.sendPreVote
 			}
-			resp, err := rn.sendRequestVote(p, args)
+			resp, err := send(peer, args)
 			if err != nil {
-				voteCh <- false
 				return
 			}
-			voteCh <- resp.VoteGranted
-		}(peer)
+			select {
+			case rn.voteCh <- voteReply{peer: peer, term: term, pre: pre, resp: resp}:
+			case <-rn.stopCh:
+			}
+		})
 	}
+	rn.countVotes() // A single voter elects itself
+}
 
-	// Wait for votes
-	for i := 0; i < len(rn.peers); i++ {
-		vote := <-voteCh
-		if vote {
-			votes++
-		}
+// handleVote records a peer's answer to our pre-vote or election.
+// Runs on the event loop.
+func (rn *RaftNode) handleVote(v voteReply) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if v.resp.Term > int64(rn.currentTerm) {
+		rn.becomeFollower(int(v.resp.Term))
+		return
 	}
+	e := rn.election
+	if e == nil || e.term != v.term || e.pre != v.pre || !v.resp.VoteGranted {
+		return // From an earlier campaign, or a refusal
+	}
+	e.granted[v.peer] = true
+	rn.countVotes()
+}
 
-	rn.mu.Lock()
-	if rn.state != Candidate {
-		rn.mu.Unlock()
+// countVotes moves the campaign on once a majority has granted its votes:
+// from the pre-vote to the election, or from the election to leadership.
+// Caller must hold rn.mu.
+func (rn *RaftNode) countVotes() {
+	e := rn.election
+	if len(e.granted) < e.needed {
 		return
 	}
+	rn.election = nil
+	if e.pre {
+		rn.campaign(false, false)
+		return
+	}
+	rn.becomeLeader()
+}
+
+// becomeLeader takes over as leader of currentTerm. Caller must hold rn.mu.
+func (rn *RaftNode) becomeLeader() {
+	rn.state = Leader
+	rn.leaderID = rn.id
+	log.Printf("[%s] Won election! Becoming Leader for term %d", rn.id, rn.currentTerm)
+	// votedFor stays set to ourselves so we cannot vote for anyone else this term
+	// Initialize leader state
+	rn.lastAck = make(map[string]time.Time)
+	rn.leaderSince = rn.clock.Now()
+	for _, p := range append(append([]string(nil), rn.peers...), rn.learners...) {
+		rn.nextIndex[p] = rn.lastLogIndex() + 1 // Index of next log entry to send
+		rn.matchIndex[p] = 0                    // Index of highest log entry known to be replicated
+	}
+	// Commit a no-op so entries from previous terms become committed
+	noop := &pb.LogEntry{Term: int64(rn.currentTerm), Type: pb.EntryType_ENTRY_NOOP}
+	rn.log = append(rn.log, noop)
+	rn.persistEntries(rn.lastLogIndex(), []*pb.LogEntry{noop})
+	rn.advanceCommitIndex()
 
-	// Majority check (myself + peers)
-	if votes > (len(rn.peers)+1)/2 {
-		rn.state = Leader
-		log.Printf("[%s] Won election! Becoming Leader for term %d", rn.id, rn.currentTerm)
-		rn.votedFor = ""
-		// Initialize leader state
-		for _, p := range rn.peers {
-			rn.nextIndex[p] = len(rn.log) // Index of next log entry to send
-			rn.matchIndex[p] = -1         // Index of highest log entry known to be replicated
-		}
-	} else {
-		// Failed election, stay candidate (loop will retry or follower)
-		// Back to follower effectively if timeout?
-		// Actually runCandidate just finishes, main loop calls it again if state is Candidate
-		// But we should verify if we stepped down
+	rn.electionTimer.Stop()
+	rn.resetHeartbeatTimer()
+	rn.broadcastAppendEntries() // Assert leadership immediately
+}
+
+// stepDown makes us a follower in the current term, abandoning any campaign,
+// and restarts the election timer if we were leading. Caller must hold rn.mu.
+func (rn *RaftNode) stepDown() {
+	if rn.state == Leader {
+		rn.heartbeatTimer.Stop()
+		rn.resetElectionTimer()
 	}
-	rn.mu.Unlock()
+	rn.state = Follower
+	rn.leaderID = ""
+	rn.election = nil
+	rn.notifyTransfer()
 }
 
-func (rn *RaftNode) runLeader() {
+// heartbeat checks the leader still has a quorum and sends the next round of
+// heartbeats. Runs on the event loop.
+func (rn *RaftNode) heartbeat() {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.state != Leader {
+		return
+	}
+	if !rn.checkQuorum() {
+		return
+	}
 	rn.resetHeartbeatTimer()
-	rn.leaderTimeoutTimer.Stop() // Stop the election timer while leader
-	for {
-		select {
-		case <-rn.heartbeatTimer.C:
-			rn.sendHeartbeats()
-			rn.resetHeartbeatTimer()
-		default:
-			rn.mu.Lock()
-			if rn.state != Leader {
-				rn.mu.Unlock()
-				return
-			}
-			rn.mu.Unlock()
-			time.Sleep(10 * time.Millisecond)
-		}
+	rn.broadcastAppendEntries()
+}
+
+// checkQuorum steps down if a majority of the voters, counting ourselves, has
+// not acknowledged us for an election timeout. A leader stranded on the
+// minority side of a partition then stops accepting writes it can never
+// commit, and clients go looking for the majority's leader instead. It
+// reports whether we are still leader. Caller must hold rn.mu.
+func (rn *RaftNode) checkQuorum() bool {
+	// Peers haven't had a chance to reply yet when we have just been elected
+	contact := rn.quorumContact()
+	if contact.Before(rn.leaderSince) {
+		contact = rn.leaderSince
+	}
+	if silent := rn.clock.Now().Sub(contact); silent >= minElectionTimeout {
+		log.Printf("[%s] No reply from a majority for %v, stepping down in term %d", rn.id, silent.Round(time.Millisecond), rn.currentTerm)
+		rn.stepDown()
+		return false
+	}
+	return true
+}
+
+// requestVoteArgs builds the RequestVote RPC for the current term, advertising
+// our last log position so voters can enforce the election restriction.
+// Caller must hold rn.mu.
+func (rn *RaftNode) requestVoteArgs() *pb.RequestVoteRequest {
+	return &pb.RequestVoteRequest{
+		Term:         int64(rn.currentTerm),
+		CandidateId:  rn.id,
+		LastLogIndex: int64(rn.lastLogIndex()),
+		LastLogTerm:  int64(rn.lastLogTerm()),
 	}
 }
 
@@ -202,91 +629,370 @@ func (rn *RaftNode) IsLeader() bool {
 	return rn.state == Leader
 }
 
-// LeaderID returns the current leader's ID if known.
+// LeaderID returns the current leader's ID, or "" if it is not known.
 // Note: This is an optimization; it might be stale.
 func (rn *RaftNode) LeaderID() string {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
-	if rn.state == Leader {
-		return rn.id
+	return rn.leaderID
+}
+
+// Leader returns the current leader's ID and gRPC address, or empty strings if
+// no leader is known.
+func (rn *RaftNode) Leader() (id, addr string) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.leaderID == rn.id {
+		return rn.id, rn.addr
+	}
+	return rn.leaderID, rn.peerAddrs[rn.leaderID]
+}
+
+// Status describes a node's view of the cluster and its log.
+type Status struct {
+	ID          string
+	State       State
+	Term        int
+	LeaderID    string
+	CommitIndex int
+	LastApplied int
+	// SnapshotIndex is the last index covered by the node's snapshot, and
+	// SnapshotTime when it was taken or installed; zero if there is none.
+	SnapshotIndex int
+	SnapshotTime  time.Time
+	// Members is the node's current cluster configuration.
+	Members []*pb.Member
+}
+
+// Status returns the node's current status.
+func (rn *RaftNode) Status() Status {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	state := rn.state
+	if state == Follower && rn.isLearner(rn.id) {
+		state = Learner
+	}
+	return Status{
+		ID:            rn.id,
+		State:         state,
+		Term:          rn.currentTerm,
+		LeaderID:      rn.leaderID,
+		CommitIndex:   rn.commitIndex,
+		LastApplied:   rn.lastApplied,
+		SnapshotIndex: rn.lastIncludedIndex,
+		SnapshotTime:  rn.lastSnapshotTime,
+		Members:       rn.config.GetMembers(),
+	}
+}
+
+// Propose appends an entry to the leader's log and blocks until it has been
+// committed and applied to the state machine through applyCh.
+func (rn *RaftNode) Propose(ctx context.Context, entry *pb.LogEntry) error {
+	return rn.propose(ctx, func() (*pb.LogEntry, error) { return entry, nil })
+}
+
+// propose has the event loop append the entry built by build, which runs
+// with rn.mu held once we know we are leader, and waits for it to be applied.
+func (rn *RaftNode) propose(ctx context.Context, build func() (*pb.LogEntry, error)) error {
+	m := proposeMsg{build: build, reply: make(chan proposed, 1)}
+	select {
+	case rn.proposeCh <- m:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rn.stopCh:
+		return ErrStopped
+	}
+	res := <-m.reply
+	if res.err != nil {
+		return res.err
+	}
+
+	select {
+	case err := <-res.p.done:
+		return err
+	case <-ctx.Done():
+		rn.mu.Lock()
+		delete(rn.proposals, res.index)
+		rn.mu.Unlock()
+		return ctx.Err()
+	case <-rn.stopCh:
+		return ErrStopped
 	}
-	// TODO: Track current leader ID in follow state
-	return "unknown"
 }
 
-func (rn *RaftNode) sendHeartbeats() {
+// appendProposal appends a proposed entry to the leader's log and starts
+// replicating it. Runs on the event loop.
+func (rn *RaftNode) appendProposal(m proposeMsg) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.state != Leader {
+		m.reply <- proposed{err: ErrNotLeader}
+		return
+	}
+	if rn.transferTarget != "" {
+		m.reply <- proposed{err: ErrLeadershipTransfer}
+		return
+	}
+	entry, err := m.build()
+	if err != nil {
+		m.reply <- proposed{err: err}
+		return
+	}
+	entry.Term = int64(rn.currentTerm)
+	rn.log = append(rn.log, entry)
+	index := rn.lastLogIndex()
+	rn.persistEntries(index, []*pb.LogEntry{entry})
+	if entry.Type == pb.EntryType_ENTRY_CONFIG {
+		rn.setConfig(entry.Config, index)
+	}
+	p := &proposal{term: rn.currentTerm, done: make(chan error, 1)}
+	rn.proposals[index] = p
+	rn.advanceCommitIndex() // single-node clusters commit immediately
+	m.reply <- proposed{index: index, p: p}
+
 	rn.broadcastAppendEntries()
 }
 
-func (rn *RaftNode) resetElectionTimer() {
-	if !rn.electionTimer.Stop() {
+// advanceCommitIndex moves commitIndex to the highest index replicated on a
+// majority of the cluster. Only entries from the current term are committed
+// by counting replicas; earlier entries are committed indirectly.
+// Caller must hold rn.mu.
+func (rn *RaftNode) advanceCommitIndex() {
+	var matched []int
+	if rn.isVoter(rn.id) {
+		matched = append(matched, rn.lastLogIndex())
+	}
+	for _, p := range rn.peers {
+		matched = append(matched, rn.matchIndex[p])
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(matched)))
+
+	if n := matched[len(matched)/2]; n > rn.commitIndex && rn.termAt(n) == rn.currentTerm {
+		rn.commitIndex = n
+		rn.signalCommit()
+	}
+
+	if rn.configIndex <= rn.commitIndex && !rn.isVoter(rn.id) {
+		// Our removal is committed; the remaining members elect a new leader
+		log.Printf("[%s] Removed from the cluster, stepping down", rn.id)
+		rn.stepDown()
+	}
+}
+
+// signalCommit wakes the applier without blocking. Caller must hold rn.mu.
+func (rn *RaftNode) signalCommit() {
+	select {
+	case rn.commitCh <- struct{}{}:
+	default:
+	}
+}
+
+// runApplier delivers committed entries to applyCh in log order.
+func (rn *RaftNode) runApplier() {
+	defer rn.wg.Done()
+	for {
 		select {
-		case <-rn.electionTimer.C:
-		default:
+		case <-rn.commitCh:
+		case <-rn.stopCh:
+			return
+		}
+		for {
+			rn.mu.Lock()
+			if snap := rn.pendingSnapshot; snap != nil {
+				rn.pendingSnapshot = nil
+				rn.mu.Unlock()
+
+				f, err := os.Open(snap.path)
+				if err != nil {
+					log.Fatalf("[%s] Failed to open installed snapshot: %v", rn.id, err)
+				}
+				msg := ApplyMsg{Index: snap.meta.LastIncludedIndex, Term: snap.meta.LastIncludedTerm, Snapshot: f, done: make(chan struct{})}
+				applied := rn.deliver(msg)
+				f.Close()
+				if !applied {
+					return
+				}
+				if snap.temp {
+					os.Remove(snap.path)
+				}
+
+				rn.mu.Lock()
+				if rn.lastApplied < msg.Index {
+					rn.lastApplied = msg.Index
+					close(rn.appliedCh)
+					rn.appliedCh = make(chan struct{})
+				}
+				rn.mu.Unlock()
+				continue
+			}
+			if rn.lastApplied < rn.lastIncludedIndex {
+				// Entries covered by a snapshot are already in the state machine
+				rn.lastApplied = rn.lastIncludedIndex
+				close(rn.appliedCh)
+				rn.appliedCh = make(chan struct{})
+			}
+			if rn.lastApplied >= rn.commitIndex {
+				rn.mu.Unlock()
+				break
+			}
+			index := rn.lastApplied + 1
+			entry := rn.entryAt(index)
+			rn.mu.Unlock()
+
+			msg := ApplyMsg{Index: index, Term: int(entry.Term), Entry: entry, done: make(chan struct{})}
+			if !rn.deliver(msg) {
+				return
+			}
+
+			rn.mu.Lock()
+			rn.lastApplied = index
+			close(rn.appliedCh)
+			rn.appliedCh = make(chan struct{})
+			if p, ok := rn.proposals[index]; ok {
+				delete(rn.proposals, index)
+				if p.term == msg.Term {
+					p.done <- nil
+				} else {
+					p.done <- ErrProposalDropped
+				}
+			}
+			rn.mu.Unlock()
 		}
 	}
-	rn.electionTimer.Reset(randomElectionTimeout())
 }
 
-func (rn *RaftNode) resetHeartbeatTimer() {
-	if !rn.heartbeatTimer.Stop() {
+// deliver hands msg to the state machine and waits until it has been applied.
+// It reports false if the node was stopped first.
+func (rn *RaftNode) deliver(msg ApplyMsg) bool {
+	select {
+	case rn.applyCh <- msg:
+	case <-rn.stopCh:
+		return false
+	}
+	select {
+	case <-msg.done:
+		return true
+	case <-rn.stopCh:
+		return false
+	}
+}
+
+func (rn *RaftNode) resetElectionTimer() {
+	if !rn.electionTimer.Stop() {
 		select {
-		case <-rn.heartbeatTimer.C:
+		case <-rn.electionTimer.C():
 		default:
 		}
 	}
-	rn.heartbeatTimer.Reset(150 * time.Millisecond)
+	rn.electionTimer.Reset(rn.randomElectionTimeout())
 }
 
-func (rn *RaftNode) resetLeaderTimeoutTimer() {
-	if !rn.leaderTimeoutTimer.Stop() {
+func (rn *RaftNode) resetHeartbeatTimer() {
+	if !rn.heartbeatTimer.Stop() {
 		select {
-		case <-rn.leaderTimeoutTimer.C:
+		case <-rn.heartbeatTimer.C():
 		default:
 		}
 	}
-	rn.leaderTimeoutTimer.Reset(randomLeaderTimeout())
+	rn.heartbeatTimer.Reset(heartbeatInterval)
 }
 
-func randomElectionTimeout() time.Duration {
-	return time.Duration(600+rand.Intn(400)) * time.Millisecond
-}
+// heartbeatInterval is how often the leader sends AppendEntries to idle peers.
+const heartbeatInterval = 150 * time.Millisecond
 
-func randomLeaderTimeout() time.Duration {
-	return time.Duration(1000+rand.Intn(500)) * time.Millisecond
+// minElectionTimeout is the shortest time a follower waits for the leader
+// before starting an election.
+const minElectionTimeout = 600 * time.Millisecond
+
+// randomElectionTimeout picks the next election timeout. Caller must hold rn.mu.
+func (rn *RaftNode) randomElectionTimeout() time.Duration {
+	return minElectionTimeout + time.Duration(rn.rand.Intn(400))*time.Millisecond
 }
 
-// Snapshot creates a snapshot of the state machine up to the given index.
-// In this simplified version, it truncates the log.
-func (rn *RaftNode) Snapshot(index int, data []byte) {
-	rn.mu.Lock()
-	defer rn.mu.Unlock()
+// Snapshot saves a snapshot of the state machine up to and including index,
+// written by write along with metadata holding the index and that entry's
+// term, and discards the log entries it covers. Entries after index are kept.
+// The state machine must already have applied index. It returns ErrNoDataDir,
+// and changes nothing, if the node has no data directory.
+func (rn *RaftNode) Snapshot(index int, write func(w io.Writer, meta storage.SnapshotMetadata) error) error {
+	if rn.dataDir == "" {
+		return ErrNoDataDir
+	}
+	rn.snapshotMu.Lock()
+	defer rn.snapshotMu.Unlock()
 
+	rn.mu.Lock()
 	if index <= rn.lastIncludedIndex {
-		return // Already snapshotted
+		rn.mu.Unlock()
+		return nil // Already snapshotted
 	}
+	if commit := rn.commitIndex; index > commit {
+		rn.mu.Unlock()
+		return fmt.Errorf("snapshot index %d is past the commit index %d", index, commit)
+	}
+	config, _ := rn.configAt(index)
+	meta := storage.SnapshotMetadata{
+		LastIncludedIndex: index,
+		LastIncludedTerm:  rn.termAt(index),
+		Config:            encodeConfig(config),
+	}
+	rn.mu.Unlock()
 
-	// Calculate actual log index
-	// Logical index = lastIncludedIndex + len(log) if we kept everything
-	// We need to find the entry at 'index'
-
-	// For now, let's assume index is valid and in our current log.
-	// realIndex := index - rn.lastIncludedIndex
-
-	// We basically just clear the log for now as a POC of compaction.
-	// In a real impl, we keep the last entry as the new "prevLog" for AppendEntries consistency.
+	// Write the snapshot without holding rn.mu so Raft keeps running, then
+	// move it into place before trimming the log, so a crash in between
+	// leaves a snapshot that restore can reconcile the log with
+	tmpPath := rn.snapshotPath() + ".new"
+	err := storage.SaveSnapshot(tmpPath, func(w io.Writer) error { return write(w, meta) })
+	if err != nil {
+		log.Printf("Failed to save snapshot: %v", err)
+		return err
+	}
 
-	rn.lastIncludedIndex = index
-	rn.lastIncludedTerm = rn.currentTerm // Simplified
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if index <= rn.lastIncludedIndex {
+		// A snapshot installed from the leader overtook this one
+		os.Remove(tmpPath)
+		return nil
+	}
+	if err := os.Rename(tmpPath, rn.snapshotPath()); err != nil {
+		return err
+	}
+	if err := syncDir(rn.dataDir); err != nil {
+		return err
+	}
+	rn.compactLog(meta, config)
+	rn.lastSnapshotTime = rn.clock.Now()
 
-	// Truncate log (keep nothing for now, or maybe keep tails)
-	rn.log = make([]*pb.LogEntry, 0)
+	log.Printf("[%s] Created snapshot at index %d (term %d), keeping %d log entries", rn.id, index, meta.LastIncludedTerm, len(rn.log))
+	return nil
+}
 
-	log.Printf("[%s] Created snapshot at index %d", rn.id, index)
+// compactLog drops the log entries covered by a snapshot, whose cluster
+// configuration is config. Caller must hold rn.mu.
+func (rn *RaftNode) compactLog(meta storage.SnapshotMetadata, config *pb.ClusterConfig) {
+	if entry := rn.entryAt(meta.LastIncludedIndex); entry != nil && int(entry.Term) == meta.LastIncludedTerm {
+		rn.log = append([]*pb.LogEntry(nil), rn.entriesFrom(meta.LastIncludedIndex+1)...)
+	} else {
+		// The snapshot is ahead of our log, or disagrees with it
+		rn.log = make([]*pb.LogEntry, 0)
+	}
+	rn.lastIncludedIndex = meta.LastIncludedIndex
+	rn.lastIncludedTerm = meta.LastIncludedTerm
+	rn.snapshotConfig = config
+	rn.refreshConfig()
+	if rn.commitIndex < rn.lastIncludedIndex {
+		rn.commitIndex = rn.lastIncludedIndex
+	}
 
-	// Write 'data' (snapshot state) to disk
-	snapshotPath := fmt.Sprintf("distdb_%s.snap", rn.id)
-	if err := storage.SaveSnapshot(snapshotPath, data); err != nil {
-		log.Printf("Failed to save snapshot: %v", err)
+	if rn.stable != nil {
+		if err := rn.stable.ResetLog(rn.lastIncludedIndex, rn.lastIncludedTerm, rn.log); err != nil {
+			log.Fatalf("[%s] Failed to compact raft log: %v", rn.id, err)
+		}
 	}
 }
+
+// snapshotPath is where the state machine snapshot is kept.
+func (rn *RaftNode) snapshotPath() string {
+	return filepath.Join(rn.dataDir, fmt.Sprintf("distdb_%s.snap", rn.id))
+}
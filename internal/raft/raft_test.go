@@ -0,0 +1,384 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// newTestNode returns a follower whose log holds one entry per term in terms.
+func newTestNode(id string, peers []string, terms ...int) *RaftNode {
+	cfg := Config{ID: id, Peers: make(map[string]string)}
+	for _, p := range peers {
+		cfg.Peers[p] = p
+	}
+	rn := newRaftNode(cfg, make(chan ApplyMsg))
+	for _, term := range terms {
+		rn.log = append(rn.log, &pb.LogEntry{Term: int64(term)})
+		rn.currentTerm = term
+	}
+	return rn
+}
+
+func TestRequestVoteElectionRestriction(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastLogIndex int64
+		lastLogTerm  int64
+		want         bool
+	}{
+		{"older last term", 5, 1, false},
+		{"same term shorter log", 2, 2, false},
+		{"same term same length", 3, 2, true},
+		{"same term longer log", 4, 2, true},
+		{"newer last term", 1, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			voter := newTestNode("voter", []string{"candidate"}, 1, 1, 2)
+			resp := voter.handleRequestVote(&pb.RequestVoteRequest{
+				Term:         3,
+				CandidateId:  "candidate",
+				LastLogIndex: tt.lastLogIndex,
+				LastLogTerm:  tt.lastLogTerm,
+			})
+			if resp.VoteGranted != tt.want {
+				t.Errorf("VoteGranted = %v, want %v", resp.VoteGranted, tt.want)
+			}
+			if resp.Term != 3 {
+				t.Errorf("Term = %d, want 3", resp.Term)
+			}
+		})
+	}
+}
+
+func TestLaggingNodeCannotBecomeLeader(t *testing.T) {
+	// n1 and n2 hold a committed entry from term 2 that n3 missed.
+	n1 := newTestNode("n1", []string{"n2", "n3"}, 1, 2)
+	n2 := newTestNode("n2", []string{"n1", "n3"}, 1, 2)
+	n3 := newTestNode("n3", []string{"n1", "n2"}, 1)
+
+	// Even after bumping its term well past the others, n3 gets no votes.
+	for round := 0; round < 5; round++ {
+		n3.currentTerm += 2
+		n3.votedFor = n3.id
+		args := n3.requestVoteArgs()
+
+		votes := 1
+		for _, voter := range []*RaftNode{n1, n2} {
+			resp := voter.handleRequestVote(args)
+			if resp.VoteGranted {
+				votes++
+			}
+		}
+		if votes > 1 {
+			t.Fatalf("round %d: lagging node received %d votes", round, votes)
+		}
+	}
+
+	// The voters adopted the higher term, and an up-to-date candidate can still win it.
+	n1.currentTerm++
+	n1.votedFor = n1.id
+	resp := n2.handleRequestVote(n1.requestVoteArgs())
+	if !resp.VoteGranted {
+		t.Fatalf("up-to-date candidate was denied a vote")
+	}
+}
+
+func TestAppendEntriesConsistencyCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		prevLogIndex  int64
+		prevLogTerm   int64
+		entries       []int // Terms of the entries sent
+		leaderCommit  int64
+		wantSuccess   bool
+		wantConflict  int64
+		wantTerms     []int
+		wantCommitted int
+	}{
+		{"heartbeat", 5, 3, nil, 5, true, 0, []int{1, 1, 2, 2, 3}, 5},
+		{"append", 5, 3, []int{3, 3}, 10, true, 0, []int{1, 1, 2, 2, 3, 3, 3}, 7},
+		{"commit capped at last new entry", 2, 1, nil, 5, true, 0, []int{1, 1, 2, 2, 3}, 2},
+		{"prev beyond log", 7, 3, []int{3}, 0, false, 6, []int{1, 1, 2, 2, 3}, 0},
+		{"prev term mismatch skips the term", 4, 3, []int{3}, 0, false, 3, []int{1, 1, 2, 2, 3}, 0},
+		{"conflict truncates", 2, 1, []int{3, 3}, 0, true, 0, []int{1, 1, 3, 3}, 0},
+		{"conflict at first entry", 0, 0, []int{1, 3}, 0, true, 0, []int{1, 3}, 0},
+		{"matching entries keep the rest", 2, 1, []int{2}, 0, true, 0, []int{1, 1, 2, 2, 3}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			follower := newTestNode("follower", []string{"leader"}, 1, 1, 2, 2, 3)
+			args := &pb.AppendEntriesRequest{
+				Term:         3,
+				LeaderId:     "leader",
+				PrevLogIndex: tt.prevLogIndex,
+				PrevLogTerm:  tt.prevLogTerm,
+				LeaderCommit: tt.leaderCommit,
+			}
+			for _, term := range tt.entries {
+				args.Entries = append(args.Entries, &pb.LogEntry{Term: int64(term)})
+			}
+			resp := follower.handleAppendEntries(args)
+			if resp.Success != tt.wantSuccess || resp.ConflictIndex != tt.wantConflict {
+				t.Errorf("Success = %v, ConflictIndex = %d, want %v, %d", resp.Success, resp.ConflictIndex, tt.wantSuccess, tt.wantConflict)
+			}
+			var terms []int
+			for _, e := range follower.log {
+				terms = append(terms, int(e.Term))
+			}
+			if fmt.Sprint(terms) != fmt.Sprint(tt.wantTerms) {
+				t.Errorf("log terms = %v, want %v", terms, tt.wantTerms)
+			}
+			if follower.commitIndex != tt.wantCommitted {
+				t.Errorf("commitIndex = %d, want %d", follower.commitIndex, tt.wantCommitted)
+			}
+		})
+	}
+}
+
+// startMemCluster starts a cluster of in-memory nodes with the given IDs on
+// a MemNetwork, and stops them when the test ends.
+func startMemCluster(t *testing.T, ids ...string) (*MemNetwork, []*RaftNode) {
+	net := NewMemNetwork(1)
+	var nodes []*RaftNode
+	for _, id := range ids {
+		cfg := Config{ID: id, Addr: id, Peers: make(map[string]string)}
+		for _, p := range ids {
+			if p != id {
+				cfg.Peers[p] = p
+			}
+		}
+		nodes = append(nodes, newMemNode(t, net, cfg))
+	}
+	for _, rn := range nodes {
+		rn.start()
+		t.Cleanup(rn.Stop)
+	}
+	return net, nodes
+}
+
+// newMemNode opens a node serving on net at cfg.Addr, with its data in a temp
+// dir unless cfg.DataDir is set, and its committed entries discarded. The
+// caller starts it.
+func newMemNode(t *testing.T, net *MemNetwork, cfg Config) *RaftNode {
+	tr := net.Transport(cfg.Addr)
+	cfg.Transport = tr
+	if cfg.DataDir == "" {
+		cfg.DataDir = t.TempDir()
+	}
+	applyCh := make(chan ApplyMsg)
+	go func() {
+		for msg := range applyCh {
+			msg.Done()
+		}
+	}()
+	rn, err := openRaftNode(cfg, applyCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Serve(rn)
+	return rn
+}
+
+// waitForLeader returns the leader once all of nodes agree on it.
+func waitForLeader(t *testing.T, nodes []*RaftNode) *RaftNode {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var leader *RaftNode
+		for _, rn := range nodes {
+			if rn.IsLeader() {
+				leader = rn
+			}
+		}
+		agreed := leader != nil
+		for _, rn := range nodes {
+			if st := rn.Status(); agreed && (st.LeaderID != leader.id || st.Term != leader.Status().Term) {
+				agreed = false
+			}
+		}
+		if agreed {
+			return leader
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("no leader elected")
+	return nil
+}
+
+func TestPreVoteStopsPartitionedNodeDisruptingLeader(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	term := leader.Status().Term
+
+	var follower *RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			follower = rn
+			break
+		}
+	}
+
+	// Cut the follower off for several election timeouts. Its pre-votes all
+	// fail, so it never starts an election of its own.
+	net.Partition([]string{follower.id})
+	time.Sleep(3 * time.Second)
+	if got := follower.Status().Term; got != term {
+		t.Fatalf("isolated node moved from term %d to %d", term, got)
+	}
+
+	// Once it reconnects the leader carries on in the same term, and the
+	// follower catches up with entries written in the meantime.
+	net.Heal()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leader.Propose(ctx, &pb.LogEntry{Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	st := leader.Status()
+	if st.State != Leader || st.Term != term {
+		t.Fatalf("leader is now %v in term %d, want leader in term %d", st.State, st.Term, term)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for follower.Status().CommitIndex < st.CommitIndex {
+		if time.Now().After(deadline) {
+			t.Fatalf("reconnected node did not catch up to index %d", st.CommitIndex)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCheckQuorumDemotesIsolatedLeader(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+
+	// Cut off from both followers, the leader steps down once a majority
+	// has been silent for an election timeout, and refuses writes.
+	net.Partition([]string{leader.id})
+	deadline := time.Now().Add(2 * time.Second)
+	for leader.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("isolated leader did not step down")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := leader.Propose(context.Background(), &pb.LogEntry{Key: "k", Value: "v"}); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("Propose on deposed leader = %v, want %v", err, ErrNotLeader)
+	}
+
+	// The majority side elects a leader of its own.
+	var rest []*RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			rest = append(rest, rn)
+		}
+	}
+	waitForLeader(t, rest)
+	net.Heal()
+}
+
+func TestReplicationOverUnreliableNetwork(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	net.SetDropRate(0.1)
+	net.SetDuplicateRate(0.2)
+	net.SetDelay(0, 20*time.Millisecond)
+
+	// Writes go through despite lost, repeated and reordered messages,
+	// retried on whichever node leads at the time.
+	for i := 0; i < 20; {
+		leader := waitForLeader(t, nodes)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"})
+		cancel()
+		if err == nil {
+			i++
+		}
+	}
+
+	// Once the network behaves again every node commits the same log.
+	net.SetDropRate(0)
+	net.SetDuplicateRate(0)
+	leader := waitForLeader(t, nodes)
+	want := leader.Status().CommitIndex
+	deadline := time.Now().Add(5 * time.Second)
+	for _, rn := range nodes {
+		for rn.Status().CommitIndex < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s did not commit up to index %d", rn.id, want)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	leader.mu.Lock()
+	defer leader.mu.Unlock()
+	for _, rn := range nodes {
+		if rn == leader {
+			continue
+		}
+		rn.mu.Lock()
+		for i := 1; i <= want; i++ {
+			if got, exp := rn.entryAt(i), leader.entryAt(i); got.Term != exp.Term || got.Type != exp.Type {
+				t.Errorf("%s entry %d is %v, want %v", rn.id, i, got, exp)
+			}
+		}
+		rn.mu.Unlock()
+	}
+}
+
+func TestStopUnblocksCallers(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+
+	// One proposal waits to commit on a leader cut off from its followers,
+	// while calls to a node that never started wait for its event loop
+	net.Partition([]string{leader.id})
+	idle := newMemNode(t, net, Config{ID: "n4", Addr: "n4"})
+	errs := make(chan error, 3)
+	go func() { errs <- leader.Propose(context.Background(), &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}) }()
+	go func() { errs <- idle.Propose(context.Background(), &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}) }()
+	go func() {
+		_, err := idle.AppendEntries(context.Background(), &pb.AppendEntriesRequest{Term: 1, LeaderId: "n1"})
+		errs <- err
+	}()
+	waitFor(t, "the proposal to be appended", func() bool {
+		leader.mu.Lock()
+		defer leader.mu.Unlock()
+		return len(leader.proposals) > 0
+	})
+
+	leader.Stop()
+	idle.Stop()
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrStopped) {
+				t.Fatalf("pending call returned %v after Stop, want %v", err, ErrStopped)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("pending call still blocked after Stop")
+		}
+	}
+
+	// Nothing is left running to take calls or fire timers
+	if _, err := leader.RequestVote(context.Background(), &pb.RequestVoteRequest{Term: 99, CandidateId: "n2"}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("RequestVote after Stop = %v, want %v", err, ErrStopped)
+	}
+	select {
+	case leader.rpcCh <- func() {}:
+		t.Fatalf("event loop still running after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if leader.electionTimer.Stop() || leader.heartbeatTimer.Stop() {
+		t.Fatalf("timers still armed after Stop")
+	}
+	if leader.IsLeader() {
+		t.Fatalf("stopped node still reports itself leader")
+	}
+}
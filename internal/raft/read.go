@@ -0,0 +1,280 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// ErrLeadershipLost is returned when a leader cannot confirm it still holds a majority.
+var ErrLeadershipLost = errors.New("could not confirm leadership")
+
+// Metrics counts how the node has served reads that required a leadership check.
+type Metrics struct {
+	LeaseReads     uint64 // Served from local state under the leader lease
+	ReadIndexReads uint64 // Confirmed with a ReadIndex heartbeat round
+}
+
+// Metrics returns a snapshot of the node's read counters.
+func (rn *RaftNode) Metrics() Metrics {
+	return Metrics{
+		LeaseReads:     rn.leaseReadCount.Load(),
+		ReadIndexReads: rn.readIndexCount.Load(),
+	}
+}
+
+// LinearizableRead blocks until the local state machine reflects every write
+// committed before the call. With lease reads enabled the leader answers from
+// its lease when it can; otherwise this uses the ReadIndex protocol, which
+// costs the leader one heartbeat round while followers ask the leader for its
+// read index.
+func (rn *RaftNode) LinearizableRead(ctx context.Context) error {
+	if rn.leaseReads {
+		return rn.LeaseRead(ctx)
+	}
+	return rn.readIndexRead(ctx)
+}
+
+// readIndexRead waits for the read index obtained from the leader (possibly ourselves).
+func (rn *RaftNode) readIndexRead(ctx context.Context) error {
+	var readIndex int
+	if rn.IsLeader() {
+		index, err := rn.readIndex(ctx)
+		if err != nil {
+			return err
+		}
+		readIndex = index
+	} else {
+		leaderID, addr := rn.Leader()
+		if leaderID == "" {
+			return ErrNotLeader
+		}
+		resp, err := rn.transport.ReadIndex(ctx, addr, &pb.ReadIndexRequest{})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return ErrNotLeader
+		}
+		readIndex = int(resp.ReadIndex)
+	}
+	rn.readIndexCount.Add(1)
+	return rn.waitApplied(ctx, readIndex)
+}
+
+// ReadIndex serves a follower's request for the index it must apply before
+// answering a linearizable read.
+func (rn *RaftNode) ReadIndex(ctx context.Context, args *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	if rn.leaseReads {
+		if index, ok := rn.leaseReadIndex(); ok {
+			return &pb.ReadIndexResponse{Success: true, ReadIndex: int64(index)}, nil
+		}
+	}
+	index, err := rn.readIndex(ctx)
+	if err != nil {
+		return &pb.ReadIndexResponse{Success: false, LeaderId: rn.LeaderID()}, nil
+	}
+	return &pb.ReadIndexResponse{Success: true, ReadIndex: int64(index)}, nil
+}
+
+// LeaseRead blocks until the local state machine is up to date for a read
+// served under the leader's lease, skipping the heartbeat round while a
+// majority has acknowledged us recently. It falls back to ReadIndex on
+// followers or when the lease has lapsed.
+func (rn *RaftNode) LeaseRead(ctx context.Context) error {
+	readIndex, ok := rn.leaseReadIndex()
+	if !ok {
+		return rn.readIndexRead(ctx)
+	}
+	rn.leaseReadCount.Add(1)
+	return rn.waitApplied(ctx, readIndex)
+}
+
+// leaseReadIndex returns the commit index if we are a leader whose lease
+// holds and who has committed an entry in its term. The lease is void while
+// we hand leadership over, since the target is elected without waiting for
+// it to expire.
+func (rn *RaftNode) leaseReadIndex() (int, bool) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.state != Leader || rn.transferTarget != "" || rn.termAt(rn.commitIndex) != rn.currentTerm || !rn.leaseValid(rn.clock.Now()) {
+		return 0, false
+	}
+	return rn.commitIndex, true
+}
+
+// Staleness reports how long ago this node last knew it was in contact with
+// the leader: for followers the last valid AppendEntries, for the leader the
+// last time a majority acknowledged it.
+func (rn *RaftNode) Staleness() time.Duration {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	var contact time.Time
+	if rn.state == Leader {
+		contact = rn.quorumContact()
+	} else if rn.leaderID != "" {
+		contact = rn.lastLeaderContact
+	}
+	if contact.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+	return rn.clock.Now().Sub(contact)
+}
+
+// quorumContact returns the latest time by which a majority of the cluster,
+// counting ourselves, had acknowledged our leadership. Caller must hold rn.mu.
+func (rn *RaftNode) quorumContact() time.Time {
+	var acks []time.Time
+	if rn.isVoter(rn.id) {
+		acks = append(acks, rn.clock.Now())
+	}
+	for _, p := range rn.peers {
+		acks = append(acks, rn.lastAck[p])
+	}
+	if len(acks) == 0 {
+		return time.Time{}
+	}
+	sort.Slice(acks, func(i, j int) bool { return acks[i].After(acks[j]) })
+	return acks[len(acks)/2]
+}
+
+// leaseValid reports whether our leadership lease covers now. Followers that
+// acknowledged us won't vote for anyone else until minElectionTimeout after
+// hearing from us, so no other leader can exist before the lease expires.
+// The lease is shortened by the drift bound since clocks don't tick in lockstep.
+// Caller must hold rn.mu.
+func (rn *RaftNode) leaseValid(now time.Time) bool {
+	contact := rn.quorumContact()
+	if contact.IsZero() || rn.leaseDrift >= minElectionTimeout {
+		return false
+	}
+	return now.Before(contact.Add(minElectionTimeout - rn.leaseDrift))
+}
+
+// leaderAlive reports whether we have heard from a leader within the minimum
+// election timeout, or are a leader that still holds its lease.
+// Caller must hold rn.mu.
+func (rn *RaftNode) leaderAlive() bool {
+	switch rn.state {
+	case Leader:
+		return rn.leaseValid(rn.clock.Now())
+	case Follower:
+		return rn.clock.Now().Sub(rn.lastLeaderContact) < minElectionTimeout
+	}
+	return false
+}
+
+// readIndex records the leader's commit index and then confirms leadership
+// with a heartbeat round, so no newer leader can have committed past it.
+func (rn *RaftNode) readIndex(ctx context.Context) (int, error) {
+	// A new leader doesn't know what is committed until its no-op commits
+	for {
+		rn.mu.Lock()
+		if rn.state != Leader {
+			rn.mu.Unlock()
+			return 0, ErrNotLeader
+		}
+		if rn.termAt(rn.commitIndex) == rn.currentTerm {
+			break
+		}
+		ch := rn.appliedCh
+		rn.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	readIndex := rn.commitIndex
+	term := rn.currentTerm
+	rn.mu.Unlock()
+
+	if !rn.confirmLeadership(ctx, term) {
+		return 0, ErrLeadershipLost
+	}
+	return readIndex, nil
+}
+
+// confirmLeadership sends a heartbeat round and reports whether a majority of
+// the cluster still recognizes us as leader for term.
+func (rn *RaftNode) confirmLeadership(ctx context.Context, term int) bool {
+	rn.mu.Lock()
+	peers := rn.peers
+	acks := 0
+	if rn.isVoter(rn.id) {
+		acks++
+	}
+	needed := rn.voters()/2 + 1
+	rn.mu.Unlock()
+	if acks >= needed {
+		return true
+	}
+
+	ackCh := make(chan bool, len(peers))
+	for _, peer := range peers {
+		go func(p string) {
+			rn.mu.Lock()
+			if rn.state != Leader || rn.currentTerm != term {
+				rn.mu.Unlock()
+				ackCh <- false
+				return
+			}
+			args := rn.appendEntriesArgs(p)
+			rn.mu.Unlock()
+
+			sent := rn.clock.Now()
+			resp, err := rn.sendAppendEntries(p, args)
+			if err != nil {
+				ackCh <- false
+				return
+			}
+			if rn.handleAppendEntriesResponse(p, args, resp, sent) {
+				rn.mu.Lock()
+				rn.replicate(p)
+				rn.mu.Unlock()
+			}
+			// Any reply in our term acknowledges us, even one rejecting the entries
+			ackCh <- resp.Term == int64(term)
+		}(peer)
+	}
+
+	for range peers {
+		select {
+		case ok := <-ackCh:
+			if ok {
+				acks++
+			}
+			if acks >= needed {
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return false
+}
+
+// waitApplied blocks until the state machine has applied index.
+func (rn *RaftNode) waitApplied(ctx context.Context, index int) error {
+	for {
+		rn.mu.Lock()
+		if rn.lastApplied >= index {
+			rn.mu.Unlock()
+			return nil
+		}
+		ch := rn.appliedCh
+		rn.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
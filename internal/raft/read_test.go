@@ -0,0 +1,250 @@
+package raft
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+func TestLinearizableReadNeedsQuorum(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+
+	// Cut off, the leader can't confirm it still leads, so it refuses reads
+	// even before it notices it has lost its quorum
+	net.Partition([]string{leader.id})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := leader.LinearizableRead(ctx); err == nil {
+		t.Fatalf("partitioned leader served a linearizable read")
+	}
+	if resp, err := leader.ReadIndex(ctx, &pb.ReadIndexRequest{}); err != nil || resp.Success {
+		t.Fatalf("partitioned leader granted a read index: %v, %v", resp, err)
+	}
+
+	// Once the others have moved on without it, it refuses them too
+	var rest []*RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			rest = append(rest, rn)
+		}
+	}
+	next := waitForLeader(t, rest)
+	if err := next.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("Propose on the new leader: %v", err)
+	}
+	if err := leader.LinearizableRead(ctx); err == nil {
+		t.Fatalf("deposed leader served a linearizable read")
+	}
+	net.Heal()
+}
+
+func TestFollowerReadWaitsForCommitted(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	var follower *RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			follower = rn
+			break
+		}
+	}
+
+	// The follower misses some writes, then reads as soon as it can reach
+	// the leader again, before the next heartbeat catches it up
+	net.Partition([]string{follower.id})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+			t.Fatalf("Propose: %v", err)
+		}
+	}
+	want := leader.Status().CommitIndex
+	net.Heal()
+	if err := follower.LinearizableRead(ctx); err != nil {
+		t.Fatalf("LinearizableRead on the follower: %v", err)
+	}
+	if got := follower.Status().LastApplied; got < want {
+		t.Fatalf("follower read with index %d applied, want at least %d", got, want)
+	}
+	if m := follower.Metrics(); m.ReadIndexReads != 1 {
+		t.Fatalf("follower counted %d ReadIndex reads, want 1", m.ReadIndexReads)
+	}
+}
+
+// testClock is the system clock, moved on by however far the test advances it.
+type testClock struct {
+	systemClock
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Add(c.offset)
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+func TestLeaseReadIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		drift    time.Duration
+		elapsed  time.Duration // Since a quorum last acknowledged the leader
+		transfer string
+		ok       bool
+	}{
+		{"fresh lease", 100 * time.Millisecond, 0, "", true},
+		{"lease about to expire", 100 * time.Millisecond, 450 * time.Millisecond, "", true},
+		{"lease expired", 100 * time.Millisecond, 500 * time.Millisecond, "", false},
+		{"drift shortens the lease", 300 * time.Millisecond, 350 * time.Millisecond, "", false},
+		{"drift as long as the election timeout", minElectionTimeout, 0, "", false},
+		{"leadership transfer", 100 * time.Millisecond, 0, "n2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &testClock{}
+			rn := newRaftNode(Config{
+				ID:              "n1",
+				Peers:           map[string]string{"n2": "n2", "n3": "n3"},
+				LeaseReads:      true,
+				LeaseDriftBound: tt.drift,
+				Clock:           clock,
+			}, make(chan ApplyMsg))
+			rn.log = append(rn.log, &pb.LogEntry{Term: 1})
+			rn.currentTerm, rn.commitIndex, rn.state = 1, 1, Leader
+			rn.transferTarget = tt.transfer
+			rn.lastAck["n2"] = clock.Now()
+			rn.lastAck["n3"] = clock.Now()
+
+			clock.advance(tt.elapsed)
+			if index, ok := rn.leaseReadIndex(); ok != tt.ok || (ok && index != 1) {
+				t.Fatalf("leaseReadIndex = %d, %v, want lease: %v", index, ok, tt.ok)
+			}
+		})
+	}
+}
+
+// startLeaseCluster is startMemCluster with lease reads enabled and a
+// testClock for each node.
+func startLeaseCluster(t *testing.T, drift time.Duration, ids ...string) (*MemNetwork, []*RaftNode, map[*RaftNode]*testClock) {
+	net := NewMemNetwork(1)
+	var nodes []*RaftNode
+	clocks := make(map[*RaftNode]*testClock)
+	for _, id := range ids {
+		cfg := Config{ID: id, Addr: id, Peers: make(map[string]string), LeaseReads: true, LeaseDriftBound: drift, Clock: &testClock{}}
+		for _, p := range ids {
+			if p != id {
+				cfg.Peers[p] = p
+			}
+		}
+		rn := newMemNode(t, net, cfg)
+		clocks[rn] = cfg.Clock.(*testClock)
+		nodes = append(nodes, rn)
+	}
+	for _, rn := range nodes {
+		rn.start()
+		t.Cleanup(rn.Stop)
+	}
+	return net, nodes, clocks
+}
+
+// waitForLease waits until leader can serve reads from its lease.
+func waitForLease(t *testing.T, leader *RaftNode) {
+	t.Helper()
+	waitFor(t, "the leader's lease", func() bool {
+		_, ok := leader.leaseReadIndex()
+		return ok
+	})
+}
+
+func TestLeaseReadFallsBackToReadIndex(t *testing.T) {
+	// A 250ms lease, which heartbeats keep renewed
+	net, nodes, clocks := startLeaseCluster(t, minElectionTimeout-250*time.Millisecond, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	waitForLease(t, leader)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Under the lease the leader reads without a heartbeat round
+	if err := leader.LinearizableRead(ctx); err != nil {
+		t.Fatalf("LinearizableRead under the lease: %v", err)
+	}
+	if m := leader.Metrics(); m != (Metrics{LeaseReads: 1}) {
+		t.Fatalf("Metrics after a lease read = %+v", m)
+	}
+
+	// Once the lease runs out it confirms leadership first. Replies now take
+	// a while, so no heartbeat renews the lease before the read.
+	net.SetDelay(20*time.Millisecond, 20*time.Millisecond)
+	clocks[leader].advance(300 * time.Millisecond)
+	if err := leader.LinearizableRead(ctx); err != nil {
+		t.Fatalf("LinearizableRead after the lease expired: %v", err)
+	}
+	if m := leader.Metrics(); m != (Metrics{LeaseReads: 1, ReadIndexReads: 1}) {
+		t.Fatalf("Metrics after the lease expired = %+v", m)
+	}
+
+	// Followers have no lease and always ask the leader
+	var follower *RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			follower = rn
+			break
+		}
+	}
+	if err := follower.LinearizableRead(ctx); err != nil {
+		t.Fatalf("LinearizableRead on a follower: %v", err)
+	}
+	if m := follower.Metrics(); m != (Metrics{ReadIndexReads: 1}) {
+		t.Fatalf("Metrics on a follower = %+v", m)
+	}
+}
+
+func TestNoLeaseReadDuringTransfer(t *testing.T) {
+	net, nodes, _ := startLeaseCluster(t, 0, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	target := caughtUpFollower(t, leader, nodes)
+	waitForLease(t, leader)
+
+	// Behind and cut off, the target holds the transfer open
+	net.Partition([]string{target.id})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	transferCtx, stopTransfer := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		_, err := leader.TransferLeadership(transferCtx, target.id)
+		done <- err
+	}()
+	waitFor(t, "the transfer to start", func() bool {
+		leader.mu.Lock()
+		defer leader.mu.Unlock()
+		return leader.transferTarget != ""
+	})
+
+	if err := leader.LinearizableRead(ctx); err != nil {
+		t.Fatalf("LinearizableRead during a transfer: %v", err)
+	}
+	if m := leader.Metrics(); m != (Metrics{ReadIndexReads: 1}) {
+		t.Fatalf("Metrics after a read during a transfer = %+v, want it confirmed with ReadIndex", m)
+	}
+	stopTransfer()
+	if err := <-done; err == nil {
+		t.Fatalf("TransferLeadership to a partitioned node succeeded")
+	}
+	net.Heal()
+}
@@ -3,11 +3,23 @@ package raft
 import (
 	"context"
 	"log"
+	"os"
+
+	"grassdb/internal/storage"
 
 	pb "github.com/ranjan42/grassdb/proto"
 )
 
+// The exported RPC handlers hand each request to the event loop, which runs
+// the matching handle method, so RPCs are processed one at a time.
+
 func (rn *RaftNode) RequestVote(ctx context.Context, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	var resp *pb.RequestVoteResponse
+	err := rn.handle(ctx, func() { resp = rn.handleRequestVote(args) })
+	return resp, err
+}
+
+func (rn *RaftNode) handleRequestVote(args *pb.RequestVoteRequest) *pb.RequestVoteResponse {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
@@ -16,75 +28,226 @@ func (rn *RaftNode) RequestVote(ctx context.Context, args *pb.RequestVoteRequest
 	// 2. If votedFor is null or candidateId, and candidate's log is at least as up-to-date as receiver's log, grant vote
 
 	if args.Term < int64(rn.currentTerm) {
-		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}, nil
+		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}
+	}
+
+	// Learners don't vote
+	if rn.isLearner(rn.id) {
+		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}
+	}
+
+	// Ignore candidates while we believe a leader is alive, without adopting their term.
+	// This keeps a lone disruptive server from deposing a healthy leader and is what
+	// makes leader leases safe: no one votes for a new leader while a lease may hold.
+	// The exception is a candidate the leader itself asked to take over, which
+	// gave up its lease first.
+	if rn.leaderAlive() && !args.LeadershipTransfer {
+		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}
 	}
 
 	// If RPC request or response contains term T > currentTerm: set currentTerm = T, convert to follower
 	if args.Term > int64(rn.currentTerm) {
-		rn.currentTerm = int(args.Term)
-		rn.state = Follower
-		rn.votedFor = ""
+		rn.becomeFollower(int(args.Term))
 	}
 
-	if rn.votedFor == "" || rn.votedFor == args.CandidateId {
-		// TODO: Check log up-to-dateness
+	if (rn.votedFor == "" || rn.votedFor == args.CandidateId) && rn.isLogUpToDate(args) {
 		rn.votedFor = args.CandidateId
+		rn.persistState()
 		rn.resetElectionTimer()
-		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: true}, nil
+		return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: true}
 	}
 
-	return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}, nil
+	return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: false}
+}
+
+// PreVote reports whether we would grant args.CandidateId our vote in
+// args.Term, without adopting the term or recording a vote. We refuse while we
+// believe a leader is alive, so a node that lost contact with it on its own
+// cannot start an election.
+func (rn *RaftNode) PreVote(ctx context.Context, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	var resp *pb.RequestVoteResponse
+	err := rn.handle(ctx, func() { resp = rn.handlePreVote(args) })
+	return resp, err
+}
+
+func (rn *RaftNode) handlePreVote(args *pb.RequestVoteRequest) *pb.RequestVoteResponse {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	granted := args.Term > int64(rn.currentTerm) &&
+		!rn.isLearner(rn.id) &&
+		!rn.leaderAlive() &&
+		rn.isLogUpToDate(args)
+	return &pb.RequestVoteResponse{Term: int64(rn.currentTerm), VoteGranted: granted}
+}
+
+// isLogUpToDate reports whether the candidate's log is at least as up-to-date
+// as ours: a later last term wins, and with equal terms the longer log wins.
+// Caller must hold rn.mu.
+func (rn *RaftNode) isLogUpToDate(args *pb.RequestVoteRequest) bool {
+	lastTerm := int64(rn.lastLogTerm())
+	if args.LastLogTerm != lastTerm {
+		return args.LastLogTerm > lastTerm
+	}
+	return args.LastLogIndex >= int64(rn.lastLogIndex())
 }
 
 func (rn *RaftNode) AppendEntries(ctx context.Context, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
+	var resp *pb.AppendEntriesResponse
+	err := rn.handle(ctx, func() { resp = rn.handleAppendEntries(args) })
+	return resp, err
+}
+
+func (rn *RaftNode) handleAppendEntries(args *pb.AppendEntriesRequest) *pb.AppendEntriesResponse {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
 	if args.Term < int64(rn.currentTerm) {
-		return &pb.AppendEntriesResponse{Term: int64(rn.currentTerm), Success: false}, nil
+		return &pb.AppendEntriesResponse{Term: int64(rn.currentTerm), Success: false}
 	}
 
 	// If RPC request or response contains term T > currentTerm: set currentTerm = T, convert to follower
 	if args.Term > int64(rn.currentTerm) {
-		rn.currentTerm = int(args.Term)
-		rn.state = Follower
-		rn.votedFor = ""
+		rn.becomeFollower(int(args.Term))
 	}
 
-	// If we are candidate/leader and receive AppendEntries from valid leader, become follower
-	if rn.state != Follower {
-		rn.state = Follower
-	}
+	// If we are candidate/leader and receive AppendEntries from valid leader, become follower,
+	// abandoning any pre-vote or election of our own
+	rn.stepDown()
+	rn.leaderID = args.LeaderId
+	rn.lastLeaderContact = rn.clock.Now()
+	rn.notifyTransfer()
 
 	rn.resetElectionTimer()
 
-	// DEBUG LOG
-	log.Printf("[%s] Received Heartbeat from %s term %d", rn.ID(), args.LeaderId, args.Term)
+	// Reply false if log doesn't contain an entry at prevLogIndex whose term matches prevLogTerm
+	prevLogIndex := int(args.PrevLogIndex)
+	if prevLogIndex > rn.lastLogIndex() {
+		return &pb.AppendEntriesResponse{
+			Term:          int64(rn.currentTerm),
+			Success:       false,
+			ConflictIndex: int64(rn.lastLogIndex() + 1),
+		}
+	}
+	if prevLogIndex >= rn.lastIncludedIndex && rn.termAt(prevLogIndex) != int(args.PrevLogTerm) {
+		// Skip back over the whole conflicting term so the leader doesn't probe one entry at a time
+		conflictTerm := rn.termAt(prevLogIndex)
+		conflictIndex := prevLogIndex
+		for conflictIndex > rn.lastIncludedIndex+1 && rn.termAt(conflictIndex-1) == conflictTerm {
+			conflictIndex--
+		}
+		return &pb.AppendEntriesResponse{
+			Term:          int64(rn.currentTerm),
+			Success:       false,
+			ConflictIndex: int64(conflictIndex),
+		}
+	}
+
+	// If an existing entry conflicts with a new one (same index but different terms),
+	// delete the existing entry and all that follow it. Append any new entries not already in the log.
+	for i, entry := range args.Entries {
+		index := prevLogIndex + 1 + i
+		if index <= rn.lastIncludedIndex {
+			continue // Already compacted into our snapshot, hence committed
+		}
+		if index <= rn.lastLogIndex() {
+			if rn.termAt(index) == int(entry.Term) {
+				continue
+			}
+			rn.log = rn.log[:index-rn.lastIncludedIndex-1]
+		}
+		rn.log = append(rn.log, args.Entries[i:]...)
+		rn.persistEntries(index, args.Entries[i:])
+		if index <= rn.configIndex || hasConfigEntry(args.Entries[i:]) {
+			// The entries set a new configuration, or truncated the one we had
+			rn.refreshConfig()
+		}
+		break
+	}
+
+	// If leaderCommit > commitIndex, set commitIndex = min(leaderCommit, index of last new entry)
+	if commit := min(int(args.LeaderCommit), prevLogIndex+len(args.Entries)); commit > rn.commitIndex {
+		rn.commitIndex = commit
+		rn.signalCommit()
+	}
 
-	return &pb.AppendEntriesResponse{Term: int64(rn.currentTerm), Success: true}, nil
+	return &pb.AppendEntriesResponse{Term: int64(rn.currentTerm), Success: true}
 }
 
+// InstallSnapshot runs in two steps when the last chunk arrives: the event
+// loop hands over the received file, which is synced and verified here
+// without holding up other RPCs, and the event loop then installs it.
 func (rn *RaftNode) InstallSnapshot(ctx context.Context, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	var resp *pb.InstallSnapshotResponse
+	var recv *snapshotReceiver
+	if err := rn.handle(ctx, func() { resp, recv = rn.handleInstallSnapshot(args) }); err != nil || recv == nil {
+		return resp, err
+	}
+
+	meta, verr := recv.verify()
+	err := rn.handle(ctx, func() { resp = rn.handleSnapshotVerified(args, recv.file.Name(), meta, verr) })
+	if err != nil {
+		os.Remove(recv.file.Name())
+	}
+	return resp, err
+}
+
+// handleInstallSnapshot stores a chunk of the leader's snapshot. Once the last
+// one is in it returns the receiver, no longer reachable from rn, for the
+// caller to verify and pass to handleSnapshotVerified before answering.
+func (rn *RaftNode) handleInstallSnapshot(args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, *snapshotReceiver) {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
 	if args.Term > int64(rn.currentTerm) {
-		rn.currentTerm = int(args.Term)
-		rn.state = Follower
-		rn.votedFor = ""
+		rn.becomeFollower(int(args.Term))
 	}
 
 	if args.Term < int64(rn.currentTerm) {
 		return &pb.InstallSnapshotResponse{Term: int64(rn.currentTerm)}, nil
 	}
 
+	rn.stepDown()
+	rn.leaderID = args.LeaderId
+	rn.lastLeaderContact = rn.clock.Now()
+	rn.notifyTransfer()
 	rn.resetElectionTimer()
 
-	// Simplified Snapshot handling:
-	// In a full implementation, we would discard the log and replace it with state.
-	// For now, we acknowledge the leader's authority.
+	if err := rn.receiveSnapshotChunk(args); err != nil {
+		log.Printf("[%s] Rejected snapshot chunk at offset %d: %v", rn.id, args.Offset, err)
+		return &pb.InstallSnapshotResponse{Term: int64(rn.currentTerm), Success: false}, nil
+	}
+	if args.Done {
+		recv := rn.recvSnapshot
+		rn.recvSnapshot = nil
+		return nil, recv
+	}
 
 	return &pb.InstallSnapshotResponse{
-		Term: int64(rn.currentTerm),
+		Term:    int64(rn.currentTerm),
+		Success: true,
 	}, nil
 }
+
+// handleSnapshotVerified answers the last chunk of a snapshot once the file
+// at path has been verified, installing it unless verification failed with
+// err or the leader has changed in the meantime.
+func (rn *RaftNode) handleSnapshotVerified(args *pb.InstallSnapshotRequest, path string, meta storage.SnapshotMetadata, err error) *pb.InstallSnapshotResponse {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if args.Term != int64(rn.currentTerm) {
+		os.Remove(path)
+		return &pb.InstallSnapshotResponse{Term: int64(rn.currentTerm)}
+	}
+	if err != nil {
+		os.Remove(path)
+	} else {
+		err = rn.installReceivedSnapshot(args, path, meta)
+	}
+	if err != nil {
+		log.Printf("[%s] Failed to install snapshot at index %d: %v", rn.id, args.LastIncludedIndex, err)
+		return &pb.InstallSnapshotResponse{Term: int64(rn.currentTerm), Success: false}
+	}
+	return &pb.InstallSnapshotResponse{Term: int64(rn.currentTerm), Success: true}
+}
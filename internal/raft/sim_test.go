@@ -0,0 +1,662 @@
+package raft
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	simSeed  = flag.Int64("sim.seed", 0, "run the simulation with this seed only")
+	simSeeds = flag.Int("sim.seeds", 4, "number of random seeds to simulate")
+	simSteps = flag.Int("sim.steps", 3000, "steps of faults and traffic per simulation")
+)
+
+// TestSimulation runs a cluster under a deterministic scheduler: a seeded
+// random number generator decides which message is delivered, dropped or
+// answered next, when timers fire, and when nodes crash, restart or are
+// partitioned. Raft's safety properties are checked after every step. A
+// failure prints the seed, which replays the exact same run.
+func TestSimulation(t *testing.T) {
+	seeds := []int64{*simSeed}
+	if *simSeed == 0 {
+		seeds = seeds[:0]
+		base := time.Now().UnixNano()
+		for i := 0; i < *simSeeds; i++ {
+			seeds = append(seeds, base+int64(i))
+		}
+	}
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	for _, seed := range seeds {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			s := newSimulation(t, seed, "n1", "n2", "n3", "n4", "n5")
+			defer s.shutdown()
+			s.chaos(*simSteps)
+			s.settle()
+		})
+	}
+}
+
+// simulation drives a cluster of nodes from a single goroutine. Nodes never
+// run their event loops; the simulation calls the loop's handlers itself.
+// Work a node starts in the background, and each RPC it sends, runs on its
+// own goroutine, but only while the simulation waits for it to finish or to
+// send an RPC, so exactly one thing happens at a time.
+type simulation struct {
+	t     *testing.T
+	seed  int64
+	rand  *rand.Rand
+	now   time.Time
+	ids   []string
+	nodes map[string]*simNode
+	ready []simStep     // Steps that may run next, in any order
+	yield chan struct{} // Signalled by a task that has finished or blocked
+
+	group    map[string]int // Partition each node is in
+	dropRate float64
+
+	leaders   map[int]string // Leader seen in each term
+	committed map[int]simCommit
+	maxTerm   int // Highest term any node has reached
+
+	step  int
+	trace []string
+}
+
+// simNode is one member of the cluster. rn is nil while it is crashed.
+type simNode struct {
+	id          string
+	dir         string
+	rn          *RaftNode
+	clock       *simClock
+	incarnation int
+}
+
+// simCommit is an entry seen committed, with the highest term any node had
+// reached at the time. Any leader of a later term must hold the entry.
+type simCommit struct {
+	entry *pb.LogEntry
+	term  int
+}
+
+// simStep is something that will happen on behalf of one incarnation of a
+// node once the clock reaches at.
+type simStep struct {
+	at          time.Time
+	node        string
+	incarnation int
+	desc        string
+	run         func(live bool) // live is false once the incarnation has crashed
+}
+
+func newSimulation(t *testing.T, seed int64, ids ...string) *simulation {
+	s := &simulation{
+		t:         t,
+		seed:      seed,
+		rand:      rand.New(rand.NewSource(seed)),
+		now:       time.Unix(0, 0),
+		ids:       ids,
+		nodes:     make(map[string]*simNode),
+		yield:     make(chan struct{}),
+		group:     make(map[string]int),
+		dropRate:  0.05,
+		leaders:   make(map[int]string),
+		committed: make(map[int]simCommit),
+	}
+	for _, id := range ids {
+		s.nodes[id] = &simNode{id: id, dir: t.TempDir()}
+		s.restart(s.nodes[id])
+	}
+	return s
+}
+
+// chaos runs steps of random traffic and faults, checking the invariants
+// after each.
+func (s *simulation) chaos(steps int) {
+	for s.step = 0; s.step < steps; s.step++ {
+		switch r := s.rand.Float64(); {
+		case r < 0.05:
+			s.propose()
+		case r < 0.06:
+			s.crashRandom()
+		case r < 0.075:
+			s.restartRandom()
+		case r < 0.085:
+			s.partition()
+		case r < 0.095:
+			s.heal()
+		default:
+			s.advance()
+		}
+		s.check()
+	}
+}
+
+// settle heals the network and restarts every node, then requires the
+// cluster to elect a leader and bring every log up to date with it.
+func (s *simulation) settle() {
+	s.heal()
+	s.dropRate = 0
+	for _, id := range s.ids {
+		if s.nodes[id].rn == nil {
+			s.restart(s.nodes[id])
+		}
+	}
+	proposed := false
+	for i := 0; i < 20000; i++ {
+		s.step++
+		if leader := s.converged(); leader != nil {
+			if proposed {
+				return
+			}
+			// Make sure the leader can still commit new entries
+			s.proposeTo(leader)
+			proposed = true
+		}
+		s.advance()
+		s.check()
+	}
+	s.fail("cluster did not converge after the faults stopped")
+}
+
+// converged returns the leader if every node follows it and has committed
+// its whole log.
+func (s *simulation) converged() *RaftNode {
+	var leader *RaftNode
+	for _, id := range s.ids {
+		rn := s.nodes[id].rn
+		rn.mu.Lock()
+		if rn.state == Leader {
+			leader = rn
+		}
+		rn.mu.Unlock()
+	}
+	if leader == nil {
+		return nil
+	}
+	leader.mu.Lock()
+	term, last := leader.currentTerm, leader.lastLogIndex()
+	leader.mu.Unlock()
+	for _, id := range s.ids {
+		rn := s.nodes[id].rn
+		rn.mu.Lock()
+		ok := rn.currentTerm == term && rn.leaderID == leader.id && rn.commitIndex == last && rn.lastLogIndex() == last
+		rn.mu.Unlock()
+		if !ok {
+			return nil
+		}
+	}
+	return leader
+}
+
+// schedule queues a step for one incarnation of a node to run after delay.
+func (s *simulation) schedule(node string, incarnation int, delay time.Duration, desc string, run func(live bool)) {
+	s.ready = append(s.ready, simStep{at: s.now.Add(delay), node: node, incarnation: incarnation, desc: desc, run: run})
+}
+
+// latency picks how long a message spends on the network. Most arrive
+// quickly, but some linger long enough to arrive after newer ones, or after
+// their sender has moved on to a later term.
+func (s *simulation) latency() time.Duration {
+	if s.rand.Intn(10) == 0 {
+		return time.Duration(s.rand.Intn(500)) * time.Millisecond
+	}
+	return time.Duration(s.rand.Intn(10)) * time.Millisecond
+}
+
+// advance runs one of the steps that are due, chosen at random. When none
+// is, it moves the clock on to the next step or timer.
+func (s *simulation) advance() {
+	var due []int
+	for i, st := range s.ready {
+		if !st.at.After(s.now) {
+			due = append(due, i)
+		}
+	}
+	if len(due) == 0 {
+		s.tick()
+		return
+	}
+	i := due[s.rand.Intn(len(due))]
+	st := s.ready[i]
+	s.ready = append(s.ready[:i], s.ready[i+1:]...)
+	s.record(st.node, st.desc)
+	n := s.nodes[st.node]
+	st.run(n.rn != nil && n.incarnation == st.incarnation)
+}
+
+// tick moves the clock to the earliest step or timer deadline and runs the
+// handlers of every timer that fires.
+func (s *simulation) tick() {
+	var next time.Time
+	for _, st := range s.ready {
+		if next.IsZero() || st.at.Before(next) {
+			next = st.at
+		}
+	}
+	for _, id := range s.ids {
+		if n := s.nodes[id]; n.rn != nil {
+			for _, tm := range n.clock.timers {
+				if tm.armed && (next.IsZero() || tm.deadline.Before(next)) {
+					next = tm.deadline
+				}
+			}
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+	s.now = next
+	for _, id := range s.ids {
+		n := s.nodes[id]
+		if n.rn == nil {
+			continue
+		}
+		for _, tm := range n.clock.timers {
+			if tm.armed && !tm.deadline.After(s.now) {
+				tm.armed = false
+				tm.c <- s.now
+			}
+		}
+		// As the event loop would
+		select {
+		case <-n.rn.electionTimer.C():
+			s.record(id, "election timeout")
+			n.rn.electionTimeout()
+		default:
+		}
+		select {
+		case <-n.rn.heartbeatTimer.C():
+			s.record(id, "heartbeat")
+			n.rn.heartbeat()
+		default:
+		}
+	}
+}
+
+// propose appends a new entry on a random leader, if there is one.
+func (s *simulation) propose() {
+	var leaders []*RaftNode
+	for _, id := range s.ids {
+		if rn := s.nodes[id].rn; rn != nil {
+			rn.mu.Lock()
+			if rn.state == Leader {
+				leaders = append(leaders, rn)
+			}
+			rn.mu.Unlock()
+		}
+	}
+	if len(leaders) > 0 {
+		s.proposeTo(leaders[s.rand.Intn(len(leaders))])
+	}
+}
+
+func (s *simulation) proposeTo(rn *RaftNode) {
+	key := fmt.Sprintf("k%d", s.step)
+	s.record(rn.id, "propose "+key)
+	rn.appendProposal(proposeMsg{
+		build: func() (*pb.LogEntry, error) {
+			return &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: key, Value: fmt.Sprint(s.seed)}, nil
+		},
+		reply: make(chan proposed, 1),
+	})
+}
+
+// crashRandom stops a random node, usually the leader if there is one, since
+// that is what forces an election.
+func (s *simulation) crashRandom() {
+	var up, leaders []*simNode
+	for _, id := range s.ids {
+		if n := s.nodes[id]; n.rn != nil {
+			up = append(up, n)
+			n.rn.mu.Lock()
+			if n.rn.state == Leader {
+				leaders = append(leaders, n)
+			}
+			n.rn.mu.Unlock()
+		}
+	}
+	// Leave a majority up most of the time so the cluster makes progress
+	if len(up) <= len(s.ids)/2+1 && (len(up) == 0 || s.rand.Intn(4) != 0) {
+		return
+	}
+	victims := up
+	if len(leaders) > 0 && s.rand.Intn(3) != 0 {
+		victims = leaders
+	}
+	n := victims[s.rand.Intn(len(victims))]
+	s.record(n.id, "crash")
+	n.rn.Stop()
+	n.rn = nil
+}
+
+func (s *simulation) restartRandom() {
+	var down []*simNode
+	for _, id := range s.ids {
+		if n := s.nodes[id]; n.rn == nil {
+			down = append(down, n)
+		}
+	}
+	if len(down) > 0 {
+		s.restart(down[s.rand.Intn(len(down))])
+	}
+}
+
+// restart brings a node up from its data directory.
+func (s *simulation) restart(n *simNode) {
+	n.incarnation++
+	n.clock = &simClock{s: s}
+	cfg := Config{
+		ID:        n.id,
+		Addr:      n.id,
+		Peers:     make(map[string]string),
+		DataDir:   n.dir,
+		Transport: &simTransport{s: s, from: n.id, incarnation: n.incarnation},
+		Clock:     n.clock,
+		Rand:      rand.New(rand.NewSource(s.rand.Int63())),
+	}
+	for _, id := range s.ids {
+		if id != n.id {
+			cfg.Peers[id] = id
+		}
+	}
+	rn, err := openRaftNode(cfg, make(chan ApplyMsg))
+	if err != nil {
+		s.fail("restart %s: %v", n.id, err)
+	}
+	tr := cfg.Transport.(*simTransport)
+	tr.rn = rn
+	rn.spawn = func(fn func()) {
+		s.schedule(n.id, tr.incarnation, 0, "start task", func(live bool) {
+			if live {
+				go func() {
+					fn()
+					s.yield <- struct{}{}
+				}()
+				s.wait(tr)
+			}
+		})
+	}
+	n.rn = rn
+	s.record(n.id, fmt.Sprintf("start, term %d, log %d", rn.currentTerm, rn.lastLogIndex()))
+}
+
+// wait blocks until the running task has finished or sent an RPC, queuing
+// any vote reply it hands the event loop on the way. A task of a crashed
+// node may hand over its vote or give up, so those are ignored either way.
+func (s *simulation) wait(tr *simTransport) {
+	for {
+		select {
+		case <-s.yield:
+			return
+		case v := <-tr.rn.voteCh:
+			if n := s.nodes[tr.from]; n.rn == nil || n.incarnation != tr.incarnation {
+				continue
+			}
+			s.schedule(tr.from, tr.incarnation, 0, "vote from "+v.peer, func(live bool) {
+				if live {
+					tr.rn.handleVote(v)
+				}
+			})
+		}
+	}
+}
+
+// partition splits the nodes into two random groups.
+func (s *simulation) partition() {
+	s.group = make(map[string]int)
+	var sides [2][]string
+	for _, id := range s.ids {
+		g := s.rand.Intn(2)
+		s.group[id] = g
+		sides[g] = append(sides[g], id)
+	}
+	s.record("", fmt.Sprintf("partition %v | %v", sides[0], sides[1]))
+}
+
+func (s *simulation) heal() {
+	s.group = make(map[string]int)
+	s.record("", "heal")
+}
+
+// shutdown stops every node and lets the tasks still blocked on RPCs exit.
+func (s *simulation) shutdown() {
+	for _, id := range s.ids {
+		if n := s.nodes[id]; n.rn != nil {
+			n.rn.Stop()
+			n.rn = nil
+		}
+	}
+	for len(s.ready) > 0 {
+		st := s.ready[len(s.ready)-1]
+		s.ready = s.ready[:len(s.ready)-1]
+		st.run(false)
+	}
+}
+
+// check verifies Raft's safety properties across the live nodes.
+func (s *simulation) check() {
+	var live []*RaftNode
+	for _, id := range s.ids {
+		if rn := s.nodes[id].rn; rn != nil {
+			rn.mu.Lock()
+			live = append(live, rn)
+		}
+	}
+	defer func() {
+		for _, rn := range live {
+			rn.mu.Unlock()
+		}
+	}()
+
+	for _, rn := range live {
+		if rn.currentTerm > s.maxTerm {
+			s.maxTerm = rn.currentTerm
+		}
+	}
+	for _, rn := range live {
+		// Election safety: at most one leader per term
+		if rn.state == Leader {
+			if other, ok := s.leaders[rn.currentTerm]; ok && other != rn.id {
+				s.fail("election safety: %s and %s both leaders in term %d", other, rn.id, rn.currentTerm)
+			}
+			s.leaders[rn.currentTerm] = rn.id
+		}
+		// State machine safety: no two nodes commit different entries at an index
+		for i := 1; i <= rn.commitIndex; i++ {
+			e := rn.entryAt(i)
+			c, ok := s.committed[i]
+			if !ok {
+				s.committed[i] = simCommit{entry: proto.Clone(e).(*pb.LogEntry), term: s.maxTerm}
+			} else if !proto.Equal(c.entry, e) {
+				s.fail("state machine safety: %s committed %v at index %d, but %v was committed before", rn.id, e, i, c.entry)
+			}
+		}
+	}
+	// Leader completeness: a leader holds every entry committed in earlier terms
+	for _, rn := range live {
+		if rn.state != Leader {
+			continue
+		}
+		for i := 1; i <= len(s.committed); i++ {
+			c := s.committed[i]
+			if rn.currentTerm > c.term && !proto.Equal(rn.entryAt(i), c.entry) {
+				s.fail("leader completeness: %s leads term %d without committed entry %d %v", rn.id, rn.currentTerm, i, c.entry)
+			}
+		}
+	}
+	// Log matching: logs that agree on an entry's term agree on everything up to it
+	for i, a := range live {
+		for _, b := range live[i+1:] {
+			last := min(a.lastLogIndex(), b.lastLogIndex())
+			for last > 0 && a.termAt(last) != b.termAt(last) {
+				last--
+			}
+			for j := 1; j <= last; j++ {
+				if !proto.Equal(a.entryAt(j), b.entryAt(j)) {
+					s.fail("log matching: %s and %s agree at index %d but differ at %d: %v vs %v", a.id, b.id, last, j, a.entryAt(j), b.entryAt(j))
+				}
+			}
+		}
+	}
+}
+
+// record adds an event to the trace printed on failure.
+func (s *simulation) record(node, event string) {
+	const keep = 40
+	if node != "" {
+		event = node + ": " + event
+	}
+	s.trace = append(s.trace, fmt.Sprintf("step %d, t=%v: %s", s.step, s.now.Sub(time.Unix(0, 0)), event))
+	if len(s.trace) > keep {
+		s.trace = s.trace[len(s.trace)-keep:]
+	}
+}
+
+func (s *simulation) fail(format string, args ...any) {
+	s.t.Helper()
+	s.t.Fatalf("seed %d, step %d: %s\nlast events:\n  %s\nreplay with: go test ./internal/raft -run TestSimulation -sim.seed=%d",
+		s.seed, s.step, fmt.Sprintf(format, args...), strings.Join(s.trace, "\n  "), s.seed)
+}
+
+// reachable reports whether a message from one node can reach another,
+// dropping it at random.
+func (s *simulation) reachable(from, to string) bool {
+	n := s.nodes[to]
+	return n.rn != nil && s.group[from] == s.group[to] && s.rand.Float64() >= s.dropRate
+}
+
+// simClock is a node's view of the simulation's virtual time.
+type simClock struct {
+	s      *simulation
+	timers []*simTimer
+}
+
+func (c *simClock) Now() time.Time { return c.s.now }
+
+func (c *simClock) NewTimer(d time.Duration) Timer {
+	t := &simTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// simTimer fires when the simulation's clock reaches its deadline.
+type simTimer struct {
+	clock    *simClock
+	c        chan time.Time
+	deadline time.Time
+	armed    bool
+}
+
+func (t *simTimer) C() <-chan time.Time { return t.c }
+
+func (t *simTimer) Reset(d time.Duration) bool {
+	was := t.armed
+	t.deadline = t.clock.s.now.Add(d)
+	t.armed = true
+	return was
+}
+
+func (t *simTimer) Stop() bool {
+	was := t.armed
+	t.armed = false
+	return was
+}
+
+// simTransport delivers one node's RPCs as steps of the simulation. The
+// request and the reply are separate steps, either of which may be lost.
+type simTransport struct {
+	s           *simulation
+	from        string
+	incarnation int
+	rn          *RaftNode
+}
+
+type simReply struct {
+	resp any
+	err  error
+}
+
+// simCall sends a request and blocks the calling task until the simulation
+// delivers the reply. It runs on the task's goroutine.
+func simCall[Resp any](t *simTransport, to string, handle func(rn *RaftNode) Resp) (Resp, error) {
+	s := t.s
+	reply := make(chan simReply, 1)
+	answer := func(r simReply) {
+		s.schedule(t.from, t.incarnation, s.latency(), "reply from "+to, func(live bool) {
+			if !live {
+				r = simReply{err: ErrUnreachable}
+			}
+			reply <- r
+			s.wait(t)
+		})
+	}
+	// Whichever incarnation of the target is up when the request arrives handles it
+	s.schedule(to, 0, s.latency(), "request from "+t.from, func(bool) {
+		if !s.reachable(t.from, to) {
+			answer(simReply{err: ErrUnreachable})
+			return
+		}
+		resp := handle(s.nodes[to].rn)
+		if !s.reachable(to, t.from) {
+			answer(simReply{err: ErrUnreachable})
+			return
+		}
+		answer(simReply{resp: resp})
+	})
+	s.yield <- struct{}{}
+
+	r := <-reply
+	if r.err != nil {
+		var zero Resp
+		return zero, r.err
+	}
+	return r.resp.(Resp), nil
+}
+
+func (t *simTransport) RequestVote(_ context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	return simCall(t, addr, func(rn *RaftNode) *pb.RequestVoteResponse { return rn.handleRequestVote(clone(args)) })
+}
+
+func (t *simTransport) PreVote(_ context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	return simCall(t, addr, func(rn *RaftNode) *pb.RequestVoteResponse { return rn.handlePreVote(clone(args)) })
+}
+
+func (t *simTransport) AppendEntries(_ context.Context, addr string, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
+	return simCall(t, addr, func(rn *RaftNode) *pb.AppendEntriesResponse { return rn.handleAppendEntries(clone(args)) })
+}
+
+func (t *simTransport) InstallSnapshot(_ context.Context, addr string, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	return simCall(t, addr, func(rn *RaftNode) *pb.InstallSnapshotResponse {
+		resp, recv := rn.handleInstallSnapshot(clone(args))
+		if recv != nil {
+			meta, err := recv.verify()
+			resp = rn.handleSnapshotVerified(args, recv.file.Name(), meta, err)
+		}
+		return resp
+	})
+}
+
+func (t *simTransport) TimeoutNow(_ context.Context, addr string, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	return simCall(t, addr, func(rn *RaftNode) *pb.TimeoutNowResponse { return rn.handleTimeoutNow(clone(args)) })
+}
+
+// ReadIndex is not simulated; reads wait on the applier, which doesn't run.
+func (t *simTransport) ReadIndex(context.Context, string, *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	return nil, ErrUnreachable
+}
+
+func (t *simTransport) Close() error { return nil }
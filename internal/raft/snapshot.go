@@ -0,0 +1,236 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"grassdb/internal/storage"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+const (
+	// snapshotChunkSize is how much of the snapshot file each InstallSnapshot RPC carries.
+	snapshotChunkSize = 512 << 10
+	// snapshotChunkTimeout bounds a single InstallSnapshot RPC.
+	snapshotChunkTimeout = 5 * time.Second
+)
+
+// pendingSnapshot is an installed snapshot waiting to be handed to the state machine.
+type pendingSnapshot struct {
+	meta storage.SnapshotMetadata
+	path string
+	temp bool // path is a temp file to remove once applied
+}
+
+// snapshotReceiver assembles a snapshot streamed by the leader in a temp file.
+type snapshotReceiver struct {
+	file   *os.File
+	index  int
+	term   int
+	offset int64 // bytes received so far
+}
+
+// sendSnapshot streams the leader's snapshot file to a peer whose nextIndex has
+// fallen behind the compacted part of the log. It reports whether the peer
+// installed it, in which case replication continues from the snapshot's index.
+func (rn *RaftNode) sendSnapshot(peer string) bool {
+	rn.mu.Lock()
+	addr, ok := rn.peerAddrs[peer]
+	if rn.state != Leader || rn.sendingSnapshot[peer] || !ok {
+		rn.mu.Unlock()
+		return false
+	}
+	term := rn.currentTerm
+	index, snapTerm := rn.lastIncludedIndex, rn.lastIncludedTerm
+	// Opened under rn.mu so the file matches lastIncludedIndex; a later
+	// snapshot replaces it by rename and leaves this handle intact
+	f, err := os.Open(rn.snapshotPath())
+	if err != nil {
+		rn.mu.Unlock()
+		log.Printf("[%s] Cannot send snapshot to %s: %v", rn.id, peer, err)
+		return false
+	}
+	rn.sendingSnapshot[peer] = true
+	rn.mu.Unlock()
+
+	defer func() {
+		f.Close()
+		rn.mu.Lock()
+		delete(rn.sendingSnapshot, peer)
+		rn.mu.Unlock()
+	}()
+
+	log.Printf("[%s] Sending snapshot at index %d to %s", rn.id, index, peer)
+	var offset int64
+	for {
+		// A fresh buffer each time: the transport may still hold the last
+		// request, for a retry or a delayed delivery
+		buf := make([]byte, snapshotChunkSize)
+		n, err := io.ReadFull(f, buf)
+		done := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !done {
+			log.Printf("[%s] Failed to read snapshot: %v", rn.id, err)
+			return false
+		}
+
+		args := &pb.InstallSnapshotRequest{
+			Term:              int64(term),
+			LeaderId:          rn.id,
+			LastIncludedIndex: int64(index),
+			LastIncludedTerm:  int64(snapTerm),
+			Data:              buf[:n],
+			Offset:            offset,
+			Done:              done,
+		}
+		sent := rn.clock.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), snapshotChunkTimeout)
+		resp, err := rn.transport.InstallSnapshot(ctx, addr, args)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to send InstallSnapshot to %s: %v", peer, err)
+			return false
+		}
+		if !rn.handleInstallSnapshotResponse(peer, args, resp, sent) {
+			return false
+		}
+		if done {
+			log.Printf("[%s] %s installed snapshot at index %d", rn.id, peer, index)
+			return true
+		}
+		offset += int64(n)
+	}
+}
+
+// handleInstallSnapshotResponse updates the leader's view of a peer after a
+// snapshot chunk and reports whether to keep sending.
+func (rn *RaftNode) handleInstallSnapshotResponse(peer string, args *pb.InstallSnapshotRequest, resp *pb.InstallSnapshotResponse, sent time.Time) bool {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if resp.Term > int64(rn.currentTerm) {
+		rn.becomeFollower(int(resp.Term))
+		rn.resetElectionTimer()
+		return false
+	}
+	if rn.state != Leader || args.Term != int64(rn.currentTerm) {
+		return false
+	}
+	if sent.After(rn.lastAck[peer]) {
+		rn.lastAck[peer] = sent
+	}
+	if !resp.Success {
+		// The peer lost track of the transfer; the next heartbeat starts over
+		return false
+	}
+
+	if args.Done {
+		if index := int(args.LastIncludedIndex); index > rn.matchIndex[peer] {
+			rn.matchIndex[peer] = index
+			rn.notifyTransfer()
+		}
+		rn.nextIndex[peer] = rn.matchIndex[peer] + 1
+	}
+	return true
+}
+
+// receiveSnapshotChunk appends a chunk from the leader to the snapshot being
+// assembled. A chunk at offset 0 starts a new transfer. Caller must hold rn.mu.
+func (rn *RaftNode) receiveSnapshotChunk(args *pb.InstallSnapshotRequest) error {
+	r := rn.recvSnapshot
+	if args.Offset == 0 {
+		rn.abortSnapshotReceive()
+		f, err := os.CreateTemp(rn.dataDir, fmt.Sprintf("distdb_%s.snap.*.recv", rn.id))
+		if err != nil {
+			return err
+		}
+		r = &snapshotReceiver{file: f, index: int(args.LastIncludedIndex), term: int(args.LastIncludedTerm)}
+		rn.recvSnapshot = r
+	} else if r == nil || r.index != int(args.LastIncludedIndex) || r.term != int(args.LastIncludedTerm) || r.offset != args.Offset {
+		return fmt.Errorf("chunk is not the next one of the snapshot being received")
+	}
+
+	if _, err := r.file.Write(args.Data); err != nil {
+		rn.abortSnapshotReceive()
+		return err
+	}
+	r.offset += int64(len(args.Data))
+	return nil
+}
+
+// abortSnapshotReceive discards a partially received snapshot. Caller must hold rn.mu.
+func (rn *RaftNode) abortSnapshotReceive() {
+	if r := rn.recvSnapshot; r != nil {
+		r.file.Close()
+		os.Remove(r.file.Name())
+		rn.recvSnapshot = nil
+	}
+}
+
+// verify syncs and closes a fully received snapshot and checks its checksums.
+// It touches only the receiver, so it runs without rn.mu.
+func (r *snapshotReceiver) verify() (storage.SnapshotMetadata, error) {
+	if err := r.file.Sync(); err != nil {
+		r.file.Close()
+		return storage.SnapshotMetadata{}, err
+	}
+	if err := r.file.Close(); err != nil {
+		return storage.SnapshotMetadata{}, err
+	}
+	return storage.VerifySnapshot(r.file.Name())
+}
+
+// installReceivedSnapshot makes the received snapshot at path, already
+// verified to hold meta, ours: it replaces the snapshot on disk, discards the
+// log entries it covers and queues it for the state machine. path is removed
+// unless it is kept. Caller must hold rn.mu.
+func (rn *RaftNode) installReceivedSnapshot(args *pb.InstallSnapshotRequest, path string, meta storage.SnapshotMetadata) error {
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(path)
+		}
+	}()
+
+	if meta.LastIncludedIndex != int(args.LastIncludedIndex) || meta.LastIncludedTerm != int(args.LastIncludedTerm) {
+		return fmt.Errorf("snapshot holds index %d term %d, expected index %d term %d",
+			meta.LastIncludedIndex, meta.LastIncludedTerm, args.LastIncludedIndex, args.LastIncludedTerm)
+	}
+	if meta.LastIncludedIndex <= max(rn.lastApplied, rn.lastIncludedIndex) {
+		return nil // We already have everything it covers
+	}
+	config, err := decodeConfig(meta.Config)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		// Taken before configurations were recorded in snapshots
+		config = rn.config
+	}
+
+	pending := &pendingSnapshot{meta: meta, path: path, temp: true}
+	if rn.dataDir != "" {
+		if err := os.Rename(path, rn.snapshotPath()); err != nil {
+			return err
+		}
+		if err := syncDir(rn.dataDir); err != nil {
+			return err
+		}
+		pending = &pendingSnapshot{meta: meta, path: rn.snapshotPath()}
+	}
+	keep = true
+	rn.compactLog(meta, config)
+	rn.lastSnapshotTime = rn.clock.Now()
+	if old := rn.pendingSnapshot; old != nil && old.temp {
+		os.Remove(old.path)
+	}
+	rn.pendingSnapshot = pending
+	rn.signalCommit()
+
+	log.Printf("[%s] Installed snapshot at index %d (term %d) from %s", rn.id, meta.LastIncludedIndex, meta.LastIncludedTerm, args.LeaderId)
+	return nil
+}
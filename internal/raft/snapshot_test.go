@@ -0,0 +1,344 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"grassdb/internal/storage"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// encodeTestSnapshot returns a snapshot at index 5 in term 1 holding data.
+func encodeTestSnapshot(t *testing.T, data map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	e, err := storage.NewSnapshotEncoder(&buf, storage.SnapshotMetadata{LastIncludedIndex: 5, LastIncludedTerm: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := e.Write(k, data[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInstallSnapshotChunks(t *testing.T) {
+	snap := encodeTestSnapshot(t, map[string]string{"a": "1", "b": "2", "c": strings.Repeat("x", 100)})
+	third := len(snap) / 3
+	chunks := [][]byte{snap[:third], snap[third : 2*third], snap[2*third:]}
+	offsets := []int64{0, int64(third), int64(2 * third)}
+	corrupt := append([]byte(nil), chunks[2]...)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	type chunk struct {
+		i       int    // Which chunk to send
+		data    []byte // Sent instead of the chunk's data when set
+		success bool
+	}
+	tests := []struct {
+		name      string
+		chunks    []chunk
+		installed bool
+		receiving bool // A transfer is still under way at the end
+	}{
+		{"in order", []chunk{{0, nil, true}, {1, nil, true}, {2, nil, true}}, true, false},
+		{"chunk skipped", []chunk{{0, nil, true}, {2, nil, false}}, false, true},
+		{"chunk repeated", []chunk{{0, nil, true}, {1, nil, true}, {1, nil, false}, {2, nil, true}}, true, false},
+		{"chunk without a start", []chunk{{1, nil, false}, {2, nil, false}}, false, false},
+		{"restarted transfer", []chunk{{0, nil, true}, {1, nil, true}, {0, nil, true}, {1, nil, true}, {2, nil, true}}, true, false},
+		{"corrupt snapshot", []chunk{{0, nil, true}, {1, nil, true}, {2, corrupt, false}}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net := NewMemNetwork(1)
+			dataDir := t.TempDir()
+			tr := net.Transport("n1")
+			rn, err := openRaftNode(Config{ID: "n1", Addr: "n1", Peers: map[string]string{"leader": "leader"}, DataDir: dataDir, Transport: tr}, make(chan ApplyMsg, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.Serve(rn)
+			rn.start()
+			defer rn.Stop()
+
+			leader := net.Transport("leader")
+			for _, c := range tt.chunks {
+				data := chunks[c.i]
+				if c.data != nil {
+					data = c.data
+				}
+				resp, err := leader.InstallSnapshot(context.Background(), "n1", &pb.InstallSnapshotRequest{
+					Term:              1,
+					LeaderId:          "leader",
+					LastIncludedIndex: 5,
+					LastIncludedTerm:  1,
+					Data:              data,
+					Offset:            offsets[c.i],
+					Done:              c.i == len(chunks)-1,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Success != c.success {
+					t.Fatalf("chunk %d at offset %d: Success = %v, want %v", c.i, offsets[c.i], resp.Success, c.success)
+				}
+			}
+
+			st := rn.Status()
+			if installed := st.SnapshotIndex == 5; installed != tt.installed {
+				t.Fatalf("SnapshotIndex = %d, installed = %v, want %v", st.SnapshotIndex, installed, tt.installed)
+			}
+			if tt.installed {
+				got, err := os.ReadFile(rn.snapshotPath())
+				if err != nil || !bytes.Equal(got, snap) {
+					t.Fatalf("installed snapshot differs from the one sent: %v", err)
+				}
+			}
+			// Nothing received is left behind once a transfer ends
+			if recv, _ := filepath.Glob(filepath.Join(dataDir, "*.recv")); (len(recv) > 0) != tt.receiving {
+				t.Fatalf("received snapshot files %v, want a transfer under way: %v", recv, tt.receiving)
+			}
+		})
+	}
+}
+
+// chunkRecorder serves a node's RPCs, keeping each snapshot chunk it receives.
+type chunkRecorder struct {
+	*RaftNode
+	mu     sync.Mutex
+	chunks []*pb.InstallSnapshotRequest
+}
+
+func (r *chunkRecorder) InstallSnapshot(ctx context.Context, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	r.mu.Lock()
+	r.chunks = append(r.chunks, args)
+	r.mu.Unlock()
+	return r.RaftNode.InstallSnapshot(ctx, args)
+}
+
+func (r *chunkRecorder) received() []*pb.InstallSnapshotRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*pb.InstallSnapshotRequest(nil), r.chunks...)
+}
+
+func TestFollowerCatchesUpFromSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults func(net *MemNetwork)
+	}{
+		{"reliable network", func(net *MemNetwork) {}},
+		{"duplicated and delayed messages", func(net *MemNetwork) {
+			net.SetDuplicateRate(1)
+			net.SetDelay(0, 20*time.Millisecond)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net, nodes := startMemCluster(t, "n1", "n2", "n3")
+			leader := waitForLeader(t, nodes)
+			var follower *RaftNode
+			for _, rn := range nodes {
+				if rn != leader {
+					follower = rn
+					break
+				}
+			}
+
+			// The leader snapshots and discards entries the follower never saw
+			net.Partition([]string{follower.id})
+			for i := 0; i < 10; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"})
+				cancel()
+				if err != nil {
+					t.Fatalf("Propose: %v", err)
+				}
+			}
+			index := leader.Status().CommitIndex
+			// Big enough to be sent in several chunks, each different
+			err := leader.Snapshot(index, func(w io.Writer, meta storage.SnapshotMetadata) error {
+				e, err := storage.NewSnapshotEncoder(w, meta, false)
+				if err != nil {
+					return err
+				}
+				for _, k := range []string{"a", "b", "c"} {
+					if err := e.Write(k, strings.Repeat(k, snapshotChunkSize)); err != nil {
+						return err
+					}
+				}
+				return e.Close()
+			})
+			if err != nil {
+				t.Fatalf("Snapshot: %v", err)
+			}
+
+			recv := &chunkRecorder{RaftNode: follower}
+			follower.transport.(*MemTransport).Serve(recv)
+			tt.faults(net)
+			net.Heal()
+			deadline := time.Now().Add(5 * time.Second)
+			for st := follower.Status(); st.SnapshotIndex < index || st.CommitIndex < index; st = follower.Status() {
+				if time.Now().After(deadline) {
+					t.Fatalf("follower reached snapshot index %d, commit index %d; want %d", st.SnapshotIndex, st.CommitIndex, index)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			got, err := os.ReadFile(follower.snapshotPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(leader.snapshotPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("follower's snapshot differs from the leader's")
+			}
+			// Every copy of every chunk carried the leader's data, however
+			// late it arrived
+			for _, c := range recv.received() {
+				end := c.Offset + int64(len(c.Data))
+				if end > int64(len(want)) || !bytes.Equal(c.Data, want[c.Offset:end]) {
+					t.Fatalf("chunk at offset %d arrived with data from elsewhere in the snapshot", c.Offset)
+				}
+			}
+
+			// Replication carries on from the snapshot
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k2", Value: "v"}); err != nil {
+				t.Fatalf("Propose: %v", err)
+			}
+			want2 := leader.Status().CommitIndex
+			for follower.Status().CommitIndex < want2 {
+				if time.Now().After(deadline) {
+					t.Fatalf("follower did not commit index %d after the snapshot", want2)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestSnapshotWithoutDataDir(t *testing.T) {
+	rn := newTestNode("n1", nil, 1, 1, 1)
+	rn.commitIndex, rn.lastApplied = 3, 3
+	err := rn.Snapshot(3, func(w io.Writer, meta storage.SnapshotMetadata) error {
+		t.Fatalf("snapshot written with no data directory to keep it in")
+		return nil
+	})
+	if !errors.Is(err, ErrNoDataDir) {
+		t.Fatalf("Snapshot = %v, want %v", err, ErrNoDataDir)
+	}
+	if st := rn.Status(); st.SnapshotIndex != 0 {
+		t.Fatalf("SnapshotIndex = %d after a refused snapshot, want 0", st.SnapshotIndex)
+	}
+}
+
+func TestSnapshotKeepsLaterEntriesAcrossRestart(t *testing.T) {
+	net := NewMemNetwork(1)
+	cfg := Config{ID: "n1", Addr: "n1", DataDir: t.TempDir()}
+	start := func() *RaftNode {
+		rn := newMemNode(t, net, cfg)
+		rn.start()
+		t.Cleanup(rn.Stop)
+		waitForLeader(t, []*RaftNode{rn})
+		return rn
+	}
+	propose := func(rn *RaftNode, n int) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for i := 0; i < n; i++ {
+			if err := rn.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+				t.Fatalf("Propose: %v", err)
+			}
+		}
+	}
+	// terms returns the terms of rn's entries from index on.
+	terms := func(rn *RaftNode, index int) []int64 {
+		rn.mu.Lock()
+		defer rn.mu.Unlock()
+		var terms []int64
+		for _, e := range rn.entriesFrom(index) {
+			terms = append(terms, e.Term)
+		}
+		return terms
+	}
+
+	// Entries from two terms, so the snapshot's term is not the current one
+	rn := start()
+	propose(rn, 5)
+	index := rn.Status().CommitIndex
+	term := rn.Status().Term
+	rn.Stop()
+	rn = start()
+	propose(rn, 5)
+	if rn.Status().Term == term {
+		t.Fatalf("term did not change across the restart")
+	}
+	last := rn.Status().CommitIndex
+	kept := terms(rn, index+1)
+
+	err := rn.Snapshot(index, func(w io.Writer, meta storage.SnapshotMetadata) error {
+		e, err := storage.NewSnapshotEncoder(w, meta, false)
+		if err != nil {
+			return err
+		}
+		return e.Close()
+	})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	check := func(rn *RaftNode, when string) {
+		t.Helper()
+		rn.mu.Lock()
+		gotIndex, gotTerm, gotLast := rn.lastIncludedIndex, rn.lastIncludedTerm, rn.lastLogIndex()
+		rn.mu.Unlock()
+		if gotIndex != index || gotTerm != int(term) {
+			t.Fatalf("%s: snapshot at index %d in term %d, want %d in term %d", when, gotIndex, gotTerm, index, term)
+		}
+		if gotLast != last || fmt.Sprint(terms(rn, index+1)) != fmt.Sprint(kept) {
+			t.Fatalf("%s: entries after the snapshot are up to %d in terms %v, want up to %d in terms %v", when, gotLast, terms(rn, index+1), last, kept)
+		}
+	}
+	check(rn, "after the snapshot")
+	f, err := os.Open(rn.snapshotPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := storage.NewSnapshotDecoder(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta := d.Metadata(); meta.LastIncludedIndex != index || meta.LastIncludedTerm != int(term) {
+		t.Fatalf("snapshot file is labelled index %d in term %d, want %d in term %d", meta.LastIncludedIndex, meta.LastIncludedTerm, index, term)
+	}
+
+	// A restart resumes from the snapshot and the entries after it
+	rn.Stop()
+	rn = newMemNode(t, net, cfg)
+	check(rn, "after restarting")
+	rn.start()
+	t.Cleanup(rn.Stop)
+	waitForLeader(t, []*RaftNode{rn})
+	propose(rn, 1)
+}
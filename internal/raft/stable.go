@@ -0,0 +1,312 @@
+package raft
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	pb "github.com/ranjan42/grassdb/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	stateFileName = "state"
+	logFileName   = "log"
+
+	// The log file starts with the index and term of the entry preceding the
+	// first record, followed by records of [length][crc32][LogEntry proto].
+	logHeaderSize = 16
+	recHeaderSize = 8
+
+	// maxLogRecordSize bounds a record's length field, so that a corrupt one
+	// can't make us allocate gigabytes.
+	maxLogRecordSize = 64 << 20
+)
+
+// ErrCorruptLog is returned by LoadLog when a damaged record is followed by
+// more data, meaning the damage is not simply a write torn by a crash.
+var ErrCorruptLog = errors.New("raft log: corrupt record")
+
+// errTornRecord marks a record cut short by the end of the file, as a crash
+// part way through appending it leaves it.
+var errTornRecord = errors.New("raft log: torn record")
+
+// StableStore keeps Raft's hard state (currentTerm, votedFor) and log on disk
+// so that a restarted node never forgets a vote or an entry it acknowledged.
+// Every write is fsynced before it returns.
+type StableStore struct {
+	dir     string
+	logFile *os.File
+	offsets []int64 // file offset of each record, offsets[i] holds index prevIndex+1+i
+	end     int64   // file offset just past the last record
+
+	prevIndex int
+}
+
+// NewStableStore opens (or creates) the stable store in dir.
+// LoadLog must be called before any entries are stored.
+func NewStableStore(dir string) (*StableStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &StableStore{dir: dir, logFile: f}, nil
+}
+
+// LoadState returns the persisted hard state, or the zero state if none was saved yet.
+func (s *StableStore) LoadState() (PersistentState, error) {
+	var st PersistentState
+	data, err := os.ReadFile(filepath.Join(s.dir, stateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+// SaveState atomically replaces the hard state on disk.
+func (s *StableStore) SaveState(st PersistentState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileSync(filepath.Join(s.dir, stateFileName), data)
+}
+
+// LoadLog reads the whole log and returns the index and term of the entry
+// preceding the first one. A torn record at the tail is discarded; a damaged
+// record anywhere else returns ErrCorruptLog.
+func (s *StableStore) LoadLog() (prevIndex, prevTerm int, entries []*pb.LogEntry, err error) {
+	info, err := s.logFile.Stat()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	size := info.Size()
+	if _, err := s.logFile.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, nil, err
+	}
+	r := bufio.NewReader(s.logFile)
+
+	var header [logHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			// Fresh log
+			return 0, 0, nil, s.ResetLog(0, 0, nil)
+		}
+		return 0, 0, nil, fmt.Errorf("read log header: %w", err)
+	}
+	prevIndex = int(binary.BigEndian.Uint64(header[0:8]))
+	prevTerm = int(binary.BigEndian.Uint64(header[8:16]))
+
+	s.prevIndex = prevIndex
+	s.offsets = s.offsets[:0]
+	offset := int64(logHeaderSize)
+	for offset < size {
+		entry, n, err := readLogRecord(r, size-offset)
+		if errors.Is(err, errTornRecord) {
+			tail := make([]byte, size-offset)
+			if _, err := s.logFile.ReadAt(tail, offset); err != nil {
+				return 0, 0, nil, err
+			}
+			if !tornTail(tail) {
+				return 0, 0, nil, fmt.Errorf("%w at offset %d: intact records follow a truncated one", ErrCorruptLog, offset)
+			}
+			break // The log ends here
+		}
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("%w at offset %d", err, offset)
+		}
+		entries = append(entries, entry)
+		s.offsets = append(s.offsets, offset)
+		offset += n
+	}
+
+	s.end = offset
+	if err := s.logFile.Truncate(offset); err != nil {
+		return 0, 0, nil, err
+	}
+	return prevIndex, prevTerm, entries, nil
+}
+
+// StoreEntries writes entries starting at index, discarding any existing
+// entries from index onwards.
+func (s *StableStore) StoreEntries(index int, entries []*pb.LogEntry) error {
+	pos := index - s.prevIndex - 1
+	if pos < 0 || pos > len(s.offsets) {
+		return fmt.Errorf("store entries at %d: log holds %d..%d", index, s.prevIndex+1, s.prevIndex+len(s.offsets))
+	}
+	if pos < len(s.offsets) {
+		s.end = s.offsets[pos]
+		s.offsets = s.offsets[:pos]
+		if err := s.logFile.Truncate(s.end); err != nil {
+			return err
+		}
+	}
+
+	var buf []byte
+	offset := s.end
+	for _, entry := range entries {
+		rec, err := encodeLogRecord(entry)
+		if err != nil {
+			return err
+		}
+		s.offsets = append(s.offsets, offset)
+		offset += int64(len(rec))
+		buf = append(buf, rec...)
+	}
+	if _, err := s.logFile.WriteAt(buf, s.end); err != nil {
+		return err
+	}
+	s.end = offset
+	return s.logFile.Sync()
+}
+
+// ResetLog atomically rewrites the log so that it holds entries following prevIndex/prevTerm.
+func (s *StableStore) ResetLog(prevIndex, prevTerm int, entries []*pb.LogEntry) error {
+	buf := make([]byte, logHeaderSize)
+	binary.BigEndian.PutUint64(buf[0:8], uint64(prevIndex))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(prevTerm))
+
+	offsets := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		rec, err := encodeLogRecord(entry)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, int64(len(buf)))
+		buf = append(buf, rec...)
+	}
+
+	path := filepath.Join(s.dir, logFileName)
+	if err := writeFileSync(path, buf); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	s.logFile.Close()
+	s.logFile = f
+	s.offsets = offsets
+	s.end = int64(len(buf))
+	s.prevIndex = prevIndex
+	return nil
+}
+
+// Close closes the underlying log file.
+func (s *StableStore) Close() error {
+	return s.logFile.Close()
+}
+
+func encodeLogRecord(entry *pb.LogEntry) ([]byte, error) {
+	data, err := proto.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	rec := make([]byte, recHeaderSize, recHeaderSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(data))
+	return append(rec, data...), nil
+}
+
+// readLogRecord decodes one record, which may use at most remaining bytes,
+// and returns its size on disk. A record that runs to the end of the file but
+// doesn't check out returns errTornRecord; one with more data after it
+// returns ErrCorruptLog.
+func readLogRecord(r io.Reader, remaining int64) (*pb.LogEntry, int64, error) {
+	if remaining < recHeaderSize {
+		return nil, 0, errTornRecord
+	}
+	var header [recHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, err
+	}
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	n := recHeaderSize + length
+	if n > remaining {
+		return nil, 0, errTornRecord
+	}
+	if length > maxLogRecordSize {
+		return nil, 0, fmt.Errorf("%w: record of %d bytes", ErrCorruptLog, length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, 0, err
+	}
+	damaged := ErrCorruptLog
+	if n == remaining {
+		damaged = errTornRecord
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, fmt.Errorf("%w: checksum mismatch", damaged)
+	}
+	entry := &pb.LogEntry{}
+	if err := proto.Unmarshal(data, entry); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", damaged, err)
+	}
+	return entry, n, nil
+}
+
+// tornTail reports whether the damaged record at the start of tail can be a
+// write torn by a crash. A crash only ever cuts short the last write, so an
+// intact record ending the file after the damage means it is corrupt instead.
+// Only a length reaching exactly to the end can start such a record, so each
+// offset costs one comparison and the checksum is rarely computed.
+func tornTail(tail []byte) bool {
+	for i := 1; i+recHeaderSize < len(tail); i++ {
+		rec := tail[i:]
+		if int(binary.BigEndian.Uint32(rec[0:4])) != len(rec)-recHeaderSize {
+			continue
+		}
+		if crc32.ChecksumIEEE(rec[recHeaderSize:]) == binary.BigEndian.Uint32(rec[4:8]) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeFileSync atomically replaces path with data, syncing both the file and its directory.
+func writeFileSync(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir fsyncs a directory so that renames in it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,106 @@
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+func TestRestartWithDamagedLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(log []byte, offsets []int64) []byte
+		want    int // Entries reloaded
+		wantErr error
+	}{
+		{"intact", func(log []byte, _ []int64) []byte { return log }, 5, nil},
+		{"torn record body", func(log []byte, _ []int64) []byte { return log[:len(log)-3] }, 4, nil},
+		{"torn record header", func(log []byte, offsets []int64) []byte { return log[:offsets[4]+5] }, 4, nil},
+		{"garbage last record", func(log []byte, offsets []int64) []byte {
+			log[len(log)-1] ^= 0xff
+			return log
+		}, 4, nil},
+		{"corrupt first record", func(log []byte, offsets []int64) []byte {
+			log[offsets[0]+recHeaderSize] ^= 0xff
+			return log
+		}, 0, ErrCorruptLog},
+		{"corrupt length past the end", func(log []byte, offsets []int64) []byte {
+			log[offsets[1]] = 0x7f
+			return log
+		}, 0, ErrCorruptLog},
+		{"corrupt length reaching the end", func(log []byte, offsets []int64) []byte {
+			// The middle record seems to be the last one, torn
+			binary.BigEndian.PutUint32(log[offsets[2]:], uint32(int64(len(log))-offsets[2]-recHeaderSize))
+			return log
+		}, 0, ErrCorruptLog},
+		{"corrupt middle record", func(log []byte, offsets []int64) []byte {
+			log[offsets[2]+recHeaderSize+1] ^= 0xff
+			return log
+		}, 0, ErrCorruptLog},
+		{"corrupt length inside the file", func(log []byte, offsets []int64) []byte {
+			log[offsets[1]+3]--
+			return log
+		}, 0, ErrCorruptLog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			dir := filepath.Join(dataDir, "distdb_n1_raft")
+			stable, err := NewStableStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, _, err := stable.LoadLog(); err != nil {
+				t.Fatal(err)
+			}
+			var entries []*pb.LogEntry
+			for i := 1; i <= 5; i++ {
+				entries = append(entries, &pb.LogEntry{Term: 1, Key: fmt.Sprintf("k%d", i), Value: "v"})
+			}
+			if err := stable.StoreEntries(1, entries); err != nil {
+				t.Fatal(err)
+			}
+			offsets := append([]int64(nil), stable.offsets...)
+			stable.Close()
+
+			path := filepath.Join(dir, logFileName)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.damage(data, offsets), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := Config{ID: "n1", Peers: map[string]string{"n2": "n2"}, DataDir: dataDir}
+			rn, err := openRaftNode(cfg, make(chan ApplyMsg))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("openRaftNode error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("openRaftNode: %v", err)
+			}
+			defer rn.Stop()
+			if len(rn.log) != tt.want {
+				t.Fatalf("reloaded %d entries, want %d", len(rn.log), tt.want)
+			}
+
+			// The torn record is gone, so the log carries on where it ends
+			rn.log = append(rn.log, &pb.LogEntry{Term: 2, Key: "next"})
+			rn.persistEntries(rn.lastLogIndex(), rn.log[len(rn.log)-1:])
+			_, _, reloaded, err := rn.stable.LoadLog()
+			if err != nil || len(reloaded) != tt.want+1 {
+				t.Fatalf("after appending, LoadLog returned %d entries, %v; want %d", len(reloaded), err, tt.want+1)
+			}
+		})
+	}
+}
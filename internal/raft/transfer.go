@@ -0,0 +1,164 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// ErrLeadershipTransfer is returned for proposals made while the leader is
+// handing leadership to another node.
+var ErrLeadershipTransfer = errors.New("leadership transfer in progress")
+
+// TransferLeadership hands leadership to target, a voter: it stops accepting
+// proposals, waits for target's log to match ours and then tells it to start
+// an election right away with TimeoutNow. It returns the new leader's ID once
+// we have stepped down. If ctx expires first the transfer is abandoned and we
+// carry on leading.
+func (rn *RaftNode) TransferLeadership(ctx context.Context, target string) (string, error) {
+	rn.mu.Lock()
+	if rn.state != Leader {
+		rn.mu.Unlock()
+		return "", ErrNotLeader
+	}
+	if target == rn.id {
+		rn.mu.Unlock()
+		return rn.id, nil
+	}
+	if !rn.isVoter(target) {
+		rn.mu.Unlock()
+		return "", fmt.Errorf("%s is not a voting member", target)
+	}
+	if rn.transferTarget != "" {
+		rn.mu.Unlock()
+		return "", ErrLeadershipTransfer
+	}
+	rn.transferTarget = target
+	term := rn.currentTerm
+	rn.mu.Unlock()
+
+	defer func() {
+		rn.mu.Lock()
+		rn.transferTarget = ""
+		rn.mu.Unlock()
+	}()
+	log.Printf("[%s] Transferring leadership to %s", rn.id, target)
+
+	// No new entries are appended from here on, so once target has our last
+	// entry its log is at least as up to date as any other voter's
+	rn.mu.Lock()
+	rn.replicate(target)
+	for {
+		if rn.state != Leader || rn.currentTerm != term {
+			rn.mu.Unlock()
+			return "", ErrNotLeader
+		}
+		if rn.matchIndex[target] >= rn.lastLogIndex() {
+			break
+		}
+		if err := rn.waitTransfer(ctx); err != nil {
+			rn.mu.Unlock()
+			return "", err
+		}
+	}
+	rn.mu.Unlock()
+
+	resp, err := rn.sendTimeoutNow(target, &pb.TimeoutNowRequest{Term: int64(term), LeaderId: rn.id})
+	if err != nil {
+		// Target may have got the request and be campaigning already
+		rn.abandonTransfer(term, 0)
+		return "", fmt.Errorf("timeout now: %w", err)
+	}
+	if !resp.Success {
+		rn.abandonTransfer(term, int(resp.Term))
+		return "", fmt.Errorf("%s refused to stand for election", target)
+	}
+
+	// Target's RequestVote carries a higher term and makes us step down; wait
+	// to hear from whoever won
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	for rn.state == Leader || rn.leaderID == "" {
+		if err := rn.waitTransfer(ctx); err != nil {
+			// Target may still win, and voters that granted it no longer
+			// honour our lease, so stop leading rather than risk stale reads
+			if rn.state == Leader && rn.currentTerm == term {
+				rn.stepDown()
+			}
+			return "", err
+		}
+	}
+	log.Printf("[%s] Leadership transferred to %s", rn.id, rn.leaderID)
+	return rn.leaderID, nil
+}
+
+// abandonTransfer stops us leading in term after a TimeoutNow that failed or
+// was refused in peerTerm, since the target may be campaigning regardless.
+// Voters that grant it their vote no longer honour our lease, so going back
+// to serving lease reads would risk stale ones.
+func (rn *RaftNode) abandonTransfer(term, peerTerm int) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if peerTerm > rn.currentTerm {
+		rn.becomeFollower(peerTerm)
+	} else if rn.state == Leader && rn.currentTerm == term {
+		rn.stepDown()
+	}
+}
+
+// waitTransfer releases rn.mu until the transfer target's match index or our
+// leader moves, or ctx is done. Caller must hold rn.mu.
+func (rn *RaftNode) waitTransfer(ctx context.Context) error {
+	ch := rn.transferCh
+	rn.mu.Unlock()
+	defer rn.mu.Lock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rn.stopCh:
+		return ErrStopped
+	}
+}
+
+// notifyTransfer wakes a TransferLeadership waiting in waitTransfer. Caller
+// must hold rn.mu.
+func (rn *RaftNode) notifyTransfer() {
+	if rn.transferTarget == "" {
+		return
+	}
+	close(rn.transferCh)
+	rn.transferCh = make(chan struct{})
+}
+
+// TimeoutNow makes us start an election immediately, because the leader is
+// handing leadership to us and our log already matches its.
+func (rn *RaftNode) TimeoutNow(ctx context.Context, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	var resp *pb.TimeoutNowResponse
+	err := rn.handle(ctx, func() { resp = rn.handleTimeoutNow(args) })
+	return resp, err
+}
+
+func (rn *RaftNode) handleTimeoutNow(args *pb.TimeoutNowRequest) *pb.TimeoutNowResponse {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if args.Term < int64(rn.currentTerm) {
+		return &pb.TimeoutNowResponse{Term: int64(rn.currentTerm), Success: false}
+	}
+	if args.Term > int64(rn.currentTerm) {
+		rn.becomeFollower(int(args.Term))
+	}
+	if rn.state != Follower || !rn.isVoter(rn.id) {
+		return &pb.TimeoutNowResponse{Term: int64(rn.currentTerm), Success: false}
+	}
+
+	log.Printf("[%s] %s is handing us leadership, starting election", rn.id, args.LeaderId)
+	resp := &pb.TimeoutNowResponse{Term: int64(rn.currentTerm), Success: true}
+	rn.campaign(false, true)
+	return resp
+}
@@ -0,0 +1,70 @@
+package raft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/ranjan42/grassdb/proto"
+)
+
+// caughtUpFollower returns a follower that has every entry in leader's log.
+func caughtUpFollower(t *testing.T, leader *RaftNode, nodes []*RaftNode) *RaftNode {
+	t.Helper()
+	var follower *RaftNode
+	for _, rn := range nodes {
+		if rn != leader {
+			follower = rn
+			break
+		}
+	}
+	waitFor(t, follower.id+" to catch up", func() bool {
+		leader.mu.Lock()
+		defer leader.mu.Unlock()
+		return leader.matchIndex[follower.id] >= leader.lastLogIndex()
+	})
+	return follower
+}
+
+func TestTransferLeadership(t *testing.T) {
+	_, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leader.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	target := caughtUpFollower(t, leader, nodes)
+
+	got, err := leader.TransferLeadership(ctx, target.id)
+	if err != nil {
+		t.Fatalf("TransferLeadership: %v", err)
+	}
+	if got != target.id {
+		t.Fatalf("TransferLeadership = %s, want %s", got, target.id)
+	}
+	if next := waitForLeader(t, nodes); next != target {
+		t.Fatalf("%s leads after the transfer, want %s", next.id, target.id)
+	}
+}
+
+func TestTransferLeadershipFailedTimeoutNow(t *testing.T) {
+	net, nodes := startMemCluster(t, "n1", "n2", "n3")
+	leader := waitForLeader(t, nodes)
+	target := caughtUpFollower(t, leader, nodes)
+
+	// The target is caught up but TimeoutNow can't reach it. It might have
+	// arrived anyway, so the leader gives up its lease and stops leading.
+	net.Partition([]string{target.id})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := leader.TransferLeadership(ctx, target.id); err == nil {
+		t.Fatalf("TransferLeadership to an unreachable node succeeded")
+	}
+	if leader.IsLeader() {
+		t.Fatalf("leader kept leading after a failed transfer")
+	}
+
+	net.Heal()
+	waitForLeader(t, nodes)
+}
@@ -2,8 +2,10 @@ package raft
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	pb "github.com/ranjan42/grassdb/proto"
@@ -11,91 +13,355 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func (rn *RaftNode) getClient(peer string) (pb.DatabaseClient, error) {
-	rn.mu.Lock()
-	defer rn.mu.Unlock()
+// Transport carries Raft RPCs between nodes. Each call is addressed to the
+// peer's address in the cluster configuration. GRPCTransport is used unless
+// Config.Transport says otherwise; MemTransport connects nodes in-process.
+type Transport interface {
+	RequestVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error)
+	PreVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error)
+	AppendEntries(ctx context.Context, addr string, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error)
+	InstallSnapshot(ctx context.Context, addr string, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error)
+	TimeoutNow(ctx context.Context, addr string, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error)
+	ReadIndex(ctx context.Context, addr string, args *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error)
+	// Close releases the transport's connections. Calls made afterwards fail.
+	Close() error
+}
 
-	if client, ok := rn.peerClients[peer]; ok {
-		return client, nil
-	}
+// Handler serves the Raft RPCs a node receives. *RaftNode implements it.
+type Handler interface {
+	RequestVote(ctx context.Context, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error)
+	PreVote(ctx context.Context, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error)
+	AppendEntries(ctx context.Context, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error)
+	InstallSnapshot(ctx context.Context, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error)
+	TimeoutNow(ctx context.Context, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error)
+	ReadIndex(ctx context.Context, args *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error)
+}
+
+// GRPCTransport sends Raft RPCs over gRPC, keeping one persistent connection
+// per peer address. The receiving side is the node's gRPC server.
+type GRPCTransport struct {
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+}
 
-	// Create new connection
-	// Note: We are leaking conn here if we don't store it to Close() later.
-	// Ideally we store Conn too, but for now ignoring cleanup.
-	conn, err := grpc.NewClient(peer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewGRPCTransport returns a transport with no connections yet; they are
+// opened on first use.
+func NewGRPCTransport() *GRPCTransport {
+	return &GRPCTransport{conns: make(map[string]*grpc.ClientConn)}
+}
+
+// Client returns a client for the node at addr, connecting if needed.
+func (t *GRPCTransport) Client(addr string) (pb.DatabaseClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, errTransportClosed
+	}
+	if conn, ok := t.conns[addr]; ok {
+		return pb.NewDatabaseClient(conn), nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, fmt.Errorf("did not connect: %v", err)
 	}
+	t.conns[addr] = conn
+	return pb.NewDatabaseClient(conn), nil
+}
+
+func (t *GRPCTransport) RequestVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.RequestVote(ctx, args)
+}
+
+func (t *GRPCTransport) PreVote(ctx context.Context, addr string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.PreVote(ctx, args)
+}
+
+func (t *GRPCTransport) AppendEntries(ctx context.Context, addr string, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.AppendEntries(ctx, args)
+}
+
+func (t *GRPCTransport) InstallSnapshot(ctx context.Context, addr string, args *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstallSnapshot(ctx, args)
+}
+
+func (t *GRPCTransport) TimeoutNow(ctx context.Context, addr string, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.TimeoutNow(ctx, args)
+}
+
+func (t *GRPCTransport) ReadIndex(ctx context.Context, addr string, args *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	c, err := t.Client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadIndex(ctx, args)
+}
+
+// Close closes every connection.
+func (t *GRPCTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for addr, conn := range t.conns {
+		conn.Close()
+		delete(t.conns, addr)
+	}
+	return nil
+}
+
+var errTransportClosed = errors.New("transport closed")
+
+// LeaderClient returns a client for the current leader, reusing the peer
+// connection Raft already holds to it. It needs the gRPC transport.
+func (rn *RaftNode) LeaderClient() (pb.DatabaseClient, error) {
+	leaderID, addr := rn.Leader()
+	if leaderID == "" || leaderID == rn.id {
+		return nil, fmt.Errorf("no remote leader known")
+	}
+	t, ok := rn.transport.(*GRPCTransport)
+	if !ok {
+		return nil, fmt.Errorf("reaching the leader's client API needs the gRPC transport")
+	}
+	return t.Client(addr)
+}
+
+// rpcTimeout bounds each RequestVote, PreVote, AppendEntries and TimeoutNow RPC.
+const rpcTimeout = 500 * time.Millisecond
 
-	client := pb.NewDatabaseClient(conn)
-	rn.peerClients[peer] = client
-	return client, nil
+// peerAddr returns the address of peer in the current configuration.
+func (rn *RaftNode) peerAddr(peer string) (string, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	addr, ok := rn.peerAddrs[peer]
+	if !ok {
+		return "", fmt.Errorf("unknown peer %s", peer)
+	}
+	return addr, nil
 }
 
 // sendRequestVote sends a RequestVote RPC to a peer.
 func (rn *RaftNode) sendRequestVote(peer string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
-	c, err := rn.getClient(peer)
+	addr, err := rn.peerAddr(peer)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
 
-	// Set a reasonable timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	return rn.transport.RequestVote(ctx, addr, args)
+}
+
+// sendPreVote asks a peer whether it would vote for us in args.Term.
+func (rn *RaftNode) sendPreVote(peer string, args *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	addr, err := rn.peerAddr(peer)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
 	defer cancel()
 
-	return c.RequestVote(ctx, args)
+	return rn.transport.PreVote(ctx, addr, args)
 }
 
 // sendAppendEntries sends an AppendEntries RPC to a peer.
 func (rn *RaftNode) sendAppendEntries(peer string, args *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
-	c, err := rn.getClient(peer)
+	addr, err := rn.peerAddr(peer)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
 
-	// Set a reasonable timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	return rn.transport.AppendEntries(ctx, addr, args)
+}
+
+// sendTimeoutNow tells a peer to start an election right away.
+func (rn *RaftNode) sendTimeoutNow(peer string, args *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	addr, err := rn.peerAddr(peer)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
 	defer cancel()
 
-	return c.AppendEntries(ctx, args)
+	return rn.transport.TimeoutNow(ctx, addr, args)
 }
 
-// BroadcastAppendEntries sends AppendEntries to all peers.
-// This is called by the Leader to send heartbeats or log entries.
+// maxAppendEntries caps how many entries are shipped in a single AppendEntries RPC.
+const maxAppendEntries = 256
+
+// broadcastAppendEntries sends AppendEntries to all peers, as heartbeats or
+// to replicate new entries. Caller must hold rn.mu.
 func (rn *RaftNode) broadcastAppendEntries() {
-	rn.mu.Lock()
-	currentTerm := rn.currentTerm
-	id := rn.id
-	// TODO: Add log info here
-	rn.mu.Unlock()
+	for _, peer := range rn.peers {
+		rn.replicate(peer)
+	}
+	for _, peer := range rn.learners {
+		rn.replicate(peer)
+	}
+}
+
+// replicator is the goroutine bringing one peer up to date. At most one runs
+// per peer, so however many proposals and heartbeats arrive, the peer only
+// has one AppendEntries in flight from us.
+type replicator struct {
+	wake chan struct{} // Asks for another round once the current one is done
+}
 
-	args := &pb.AppendEntriesRequest{
-		Term:     int64(currentTerm),
-		LeaderId: id,
-		// PrevLogIndex: ...
-		// PrevLogTerm: ...
-		// Entries: ...
-		// LeaderCommit: ...
+// replicate starts a replicator for peer, or wakes the one already running.
+// Caller must hold rn.mu.
+func (rn *RaftNode) replicate(peer string) {
+	if r, ok := rn.replicators[peer]; ok {
+		select {
+		case r.wake <- struct{}{}:
+		default: // Already woken
+		}
+		return
 	}
+	r := &replicator{wake: make(chan struct{}, 1)}
+	rn.replicators[peer] = r
+	rn.spawn(func() { rn.runReplicator(peer, r) })
+}
 
-	for _, peer := range rn.peers {
-		// Send in goroutine
-		go func(p string) {
-			resp, err := rn.sendAppendEntries(p, args)
-			if err != nil {
-				log.Printf("Failed to send AppendEntries to %s: %v", p, err)
+// runReplicator sends rounds of AppendEntries to peer for as long as it is
+// woken, and exits once a round ends with no wake-up pending.
+func (rn *RaftNode) runReplicator(peer string, r *replicator) {
+	for {
+		rn.replicateTo(peer)
+
+		rn.mu.Lock()
+		select {
+		case <-r.wake:
+			rn.mu.Unlock()
+			continue
+		default:
+		}
+		// replicate only wakes us while it holds rn.mu, so no wake-up is lost
+		delete(rn.replicators, peer)
+		rn.mu.Unlock()
+		return
+	}
+}
+
+// replicateTo brings a single peer up to date with the leader's log. It keeps
+// sending until the peer has everything, backing off nextIndex on mismatches.
+func (rn *RaftNode) replicateTo(peer string) {
+	for {
+		rn.mu.Lock()
+		if rn.state != Leader || rn.sendingSnapshot[peer] || !rn.isMember(peer) {
+			rn.mu.Unlock()
+			return
+		}
+		if rn.nextIndex[peer] <= rn.lastIncludedIndex {
+			// The entries the peer needs were compacted away
+			rn.mu.Unlock()
+			if !rn.sendSnapshot(peer) {
 				return
 			}
-			rn.mu.Lock()
-			defer rn.mu.Unlock()
-
-			// If response contains higher term, convert to follower
-			if resp.Term > int64(rn.currentTerm) {
-				rn.currentTerm = int(resp.Term)
-				rn.state = Follower
-				rn.votedFor = ""
-				rn.resetElectionTimer() // ensure we don't start election immediately
-			}
-		}(peer)
+			continue
+		}
+		args := rn.appendEntriesArgs(peer)
+		rn.mu.Unlock()
+
+		sent := rn.clock.Now()
+		resp, err := rn.sendAppendEntries(peer, args)
+		if err != nil {
+			log.Printf("Failed to send AppendEntries to %s: %v", peer, err)
+			return
+		}
+
+		if !rn.handleAppendEntriesResponse(peer, args, resp, sent) {
+			return
+		}
+	}
+}
+
+// appendEntriesArgs builds the next AppendEntries RPC for peer, starting at its nextIndex.
+// Caller must hold rn.mu.
+func (rn *RaftNode) appendEntriesArgs(peer string) *pb.AppendEntriesRequest {
+	prevLogIndex := rn.nextIndex[peer] - 1
+	entries := rn.entriesFrom(prevLogIndex + 1)
+	if len(entries) > maxAppendEntries {
+		entries = entries[:maxAppendEntries]
+	}
+	return &pb.AppendEntriesRequest{
+		Term:         int64(rn.currentTerm),
+		LeaderId:     rn.id,
+		PrevLogIndex: int64(prevLogIndex),
+		PrevLogTerm:  int64(rn.termAt(prevLogIndex)),
+		Entries:      entries,
+		LeaderCommit: int64(rn.commitIndex),
+	}
+}
+
+// handleAppendEntriesResponse updates the leader's view of a peer's log and
+// reports whether another AppendEntries should be sent to it right away.
+// sent is when the request went out, which bounds the leader's lease.
+func (rn *RaftNode) handleAppendEntriesResponse(peer string, args *pb.AppendEntriesRequest, resp *pb.AppendEntriesResponse, sent time.Time) bool {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	// If response contains higher term, convert to follower
+	if resp.Term > int64(rn.currentTerm) {
+		rn.becomeFollower(int(resp.Term))
+		rn.resetElectionTimer() // ensure we don't start election immediately
+		return false
+	}
+
+	// Ignore replies to RPCs from an earlier term
+	if rn.state != Leader || args.Term != int64(rn.currentTerm) {
+		return false
+	}
+
+	// The peer acknowledged us as leader at some point after sent
+	if sent.After(rn.lastAck[peer]) {
+		rn.lastAck[peer] = sent
+	}
+
+	if resp.Success {
+		if match := int(args.PrevLogIndex) + len(args.Entries); match > rn.matchIndex[peer] {
+			rn.matchIndex[peer] = match
+			rn.nextIndex[peer] = match + 1
+			rn.advanceCommitIndex()
+			rn.notifyTransfer()
+		}
+		return rn.nextIndex[peer] <= rn.lastLogIndex()
+	}
+
+	// Log mismatch: decrement nextIndex and retry. Replies to stale probes are ignored.
+	if int(args.PrevLogIndex)+1 != rn.nextIndex[peer] {
+		return false
+	}
+	next := rn.nextIndex[peer] - 1
+	if resp.ConflictIndex > 0 && int(resp.ConflictIndex) < next {
+		next = int(resp.ConflictIndex)
+	}
+	if next <= rn.matchIndex[peer] {
+		// The peer lost entries it had acknowledged, e.g. its disk was replaced
+		log.Printf("[%s] %s no longer has entries up to %d, resending from %d", rn.id, peer, rn.matchIndex[peer], next)
+		rn.matchIndex[peer] = 0
 	}
+	rn.nextIndex[peer] = max(next, rn.matchIndex[peer]+1, 1)
+	return true
 }
@@ -2,7 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	pb "github.com/ranjan42/grassdb/proto"
 )
@@ -18,7 +20,28 @@ func (h *httpServer) handleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.db.Get(r.Context(), &pb.GetRequest{Key: key})
+	req := &pb.GetRequest{Key: key}
+	switch consistency := r.URL.Query().Get("consistency"); consistency {
+	case "", "linearizable":
+		req.Consistency = pb.ReadConsistency_READ_LINEARIZABLE
+	case "lease":
+		req.Consistency = pb.ReadConsistency_READ_LEASE
+	case "stale":
+		req.Consistency = pb.ReadConsistency_READ_STALE
+	default:
+		http.Error(w, "invalid consistency: "+consistency, http.StatusBadRequest)
+		return
+	}
+	if s := r.URL.Query().Get("max_staleness"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, "invalid max_staleness", http.StatusBadRequest)
+			return
+		}
+		req.MaxStalenessMs = d.Milliseconds()
+	}
+
+	resp, err := h.db.Get(r.Context(), req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -54,11 +77,59 @@ func (h *httpServer) handleSet(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+func (h *httpServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.db.Delete(r.Context(), &pb.DeleteRequest{Key: key})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleMetrics exposes read path counters in the Prometheus text format.
+func (h *httpServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	m := h.db.raftNode.Metrics()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP grassdb_leadership_reads_total Reads that required confirming leadership, by how it was confirmed.")
+	fmt.Fprintln(w, "# TYPE grassdb_leadership_reads_total counter")
+	fmt.Fprintf(w, "grassdb_leadership_reads_total{path=\"lease\"} %d\n", m.LeaseReads)
+	fmt.Fprintf(w, "grassdb_leadership_reads_total{path=\"read_index\"} %d\n", m.ReadIndexReads)
+}
+
+// handleStatus reports the node's Raft and snapshot status.
+func (h *httpServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.db.Status(r.Context(), &pb.StatusRequest{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 func StartHTTPServer(addr string, db *DatabaseServer) error {
 	h := &httpServer{db: db}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/get", h.handleGet)
 	mux.HandleFunc("/set", h.handleSet)
+	mux.HandleFunc("/delete", h.handleDelete)
+	mux.HandleFunc("/metrics", h.handleMetrics)
+	mux.HandleFunc("/status", h.handleStatus)
 
 	// Enable CORS for frontend
 	handler := corsMiddleware(mux)
@@ -69,7 +140,7 @@ func StartHTTPServer(addr string, db *DatabaseServer) error {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -2,9 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"grassdb/internal/raft"
 	"grassdb/internal/storage"
@@ -12,60 +18,269 @@ import (
 	pb "github.com/ranjan42/grassdb/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type DatabaseServer struct {
 	pb.UnimplementedDatabaseServer
 	store    *storage.Store
 	raftNode *raft.RaftNode
+	cfg      Config
+
+	snapshotting       atomic.Bool  // a snapshot is being taken
+	walBytesAtSnapshot atomic.Int64 // store.WALBytesWritten when the last snapshot was taken
+}
+
+// Config holds the settings for a DatabaseServer.
+type Config struct {
+	// DataDir is where the WAL and snapshots are kept.
+	DataDir string
+	// WAL controls when the WAL is fsynced.
+	WAL storage.WALConfig
+	// HTTPAddrs maps node IDs (including our own) to their HTTP addresses,
+	// used to point HTTP clients at the leader.
+	HTTPAddrs map[string]string
+	// ForwardWrites makes followers proxy writes to the leader instead of
+	// answering "Not Leader".
+	ForwardWrites bool
+	// SnapshotEntries takes a snapshot in the background once this many
+	// entries have been applied since the last one. 0 disables it.
+	SnapshotEntries int
+	// SnapshotWALBytes takes a snapshot in the background once this many
+	// bytes have been written to the WAL since the last one. 0 disables it.
+	SnapshotWALBytes int64
+	// SnapshotCompression compresses the records of snapshots this node takes.
+	SnapshotCompression bool
 }
 
-func NewServer(rn *raft.RaftNode) *DatabaseServer {
-	store, err := storage.NewStoreWithWAL(fmt.Sprintf("distdb_%s.wal", rn.ID())) // Use unique WAL per node
+// forwardedKey marks a request already proxied by a follower, so it is never forwarded twice.
+const forwardedKey = "x-grassdb-forwarded"
+
+// proposeTimeout bounds how long a write waits to be committed.
+const proposeTimeout = 5 * time.Second
+
+func NewServer(rn *raft.RaftNode, applyCh <-chan raft.ApplyMsg, cfg Config) *DatabaseServer {
+	// Start from the last snapshot, if any, and replay the WAL on top of it
+	snapshotPath := filepath.Join(cfg.DataDir, fmt.Sprintf("distdb_%s.snap", rn.ID()))
+	var snapshot io.Reader
+	if f, err := os.Open(snapshotPath); err == nil {
+		defer f.Close()
+		log.Printf("[%s] Loading snapshot from disk...", rn.ID())
+		snapshot = f
+	}
+
+	store, err := storage.NewStoreWithWAL(filepath.Join(cfg.DataDir, fmt.Sprintf("distdb_%s.wal", rn.ID())), snapshot, cfg.WAL) // Use unique WAL per node
 	if err != nil {
 		log.Fatalf("failed to initialize WAL: %v", err)
 	}
 
-	// Attempt to load snapshot
-	snapshotPath := fmt.Sprintf("distdb_%s.snap", rn.ID())
-	if data, err := storage.LoadSnapshot(snapshotPath); err == nil {
-		log.Printf("[%s] Loading snapshot from disk...", rn.ID())
-		if err := store.RestoreFromSnapshot(data); err != nil {
-			log.Printf("Failed to restore snapshot: %v", err)
+	s := &DatabaseServer{
+		store:    store,
+		raftNode: rn,
+		cfg:      cfg,
+	}
+	go s.runApplyLoop(applyCh)
+	return s
+}
+
+// runApplyLoop applies committed Raft entries to the store.
+func (s *DatabaseServer) runApplyLoop(applyCh <-chan raft.ApplyMsg) {
+	for msg := range applyCh {
+		if msg.Snapshot != nil {
+			// The leader sent us a snapshot that supersedes our state
+			if err := s.store.RestoreFromSnapshot(msg.Snapshot); err != nil {
+				log.Fatalf("[%s] Failed to restore snapshot at index %d: %v", s.raftNode.ID(), msg.Index, err)
+			}
+			log.Printf("[%s] Restored snapshot at index %d", s.raftNode.ID(), msg.Index)
+			msg.Done()
+			continue
 		}
+		var err error
+		switch msg.Entry.Type {
+		case pb.EntryType_ENTRY_PUT:
+			err = s.store.Set(msg.Index, msg.Entry.Key, msg.Entry.Value)
+		case pb.EntryType_ENTRY_DELETE:
+			err = s.store.Delete(msg.Index, msg.Entry.Key)
+		case pb.EntryType_ENTRY_NOOP, pb.EntryType_ENTRY_CONFIG:
+			// Nothing to apply; Raft handles configuration changes
+		}
+		if err != nil {
+			// Carrying on would acknowledge a write the WAL doesn't hold
+			log.Fatalf("[%s] Failed to apply entry %d: %v", s.raftNode.ID(), msg.Index, err)
+		}
+		msg.Done()
+		s.maybeSnapshot()
 	}
+}
 
-	return &DatabaseServer{
-		store:    store,
-		raftNode: rn,
+// maybeSnapshot starts a snapshot in the background once the store has moved
+// past the configured thresholds since the last one. Writes carry on while
+// it is taken.
+func (s *DatabaseServer) maybeSnapshot() {
+	entries := s.store.LastIndex() - s.raftNode.Status().SnapshotIndex
+	walBytes := s.store.WALBytesWritten() - s.walBytesAtSnapshot.Load()
+	due := (s.cfg.SnapshotEntries > 0 && entries >= s.cfg.SnapshotEntries) ||
+		(s.cfg.SnapshotWALBytes > 0 && walBytes >= s.cfg.SnapshotWALBytes)
+	if !due || !s.snapshotting.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		log.Printf("[%s] Taking automatic snapshot (%d entries, %d WAL bytes since the last one)", s.raftNode.ID(), entries, walBytes)
+		err := s.snapshot()
+		if errors.Is(err, raft.ErrNoDataDir) {
+			// Leave snapshotting set so no more are attempted
+			log.Printf("[%s] Automatic snapshots disabled: %v", s.raftNode.ID(), err)
+			return
+		}
+		if err != nil {
+			log.Printf("[%s] Automatic snapshot failed: %v", s.raftNode.ID(), err)
+		}
+		s.snapshotting.Store(false)
+	}()
+}
+
+// snapshot snapshots the store at its last applied index, hands it to Raft
+// and drops the WAL segments it covers. The WAL is left alone if Raft didn't
+// keep the snapshot.
+func (s *DatabaseServer) snapshot() error {
+	walBytes := s.store.WALBytesWritten()
+	view := s.store.GetSnapshot()
+	index := view.Index
+	err := s.raftNode.Snapshot(index, func(w io.Writer, meta storage.SnapshotMetadata) error {
+		return view.Encode(w, meta, s.cfg.SnapshotCompression)
+	})
+	if err != nil {
+		return err
+	}
+	s.walBytesAtSnapshot.Store(walBytes)
+
+	if err := s.store.CompactWAL(index); err != nil {
+		log.Printf("[%s] Failed to compact WAL: %v", s.raftNode.ID(), err)
 	}
+	return nil
 }
 
+// readTimeout bounds how long a read waits for the ReadIndex round.
+const readTimeout = 2 * time.Second
+
 func (s *DatabaseServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	switch req.Consistency {
+	case pb.ReadConsistency_READ_LINEARIZABLE:
+		// Wait until we've applied everything committed so far
+		if err := s.raftNode.LinearizableRead(ctx); err != nil {
+			return nil, fmt.Errorf("linearizable read failed: %w", err)
+		}
+	case pb.ReadConsistency_READ_LEASE:
+		if err := s.raftNode.LeaseRead(ctx); err != nil {
+			return nil, fmt.Errorf("lease read failed: %w", err)
+		}
+	case pb.ReadConsistency_READ_STALE:
+		maxStaleness := time.Duration(req.MaxStalenessMs) * time.Millisecond
+		if staleness := s.raftNode.Staleness(); maxStaleness > 0 && staleness > maxStaleness {
+			return nil, fmt.Errorf("node is too stale: last heard from leader %v ago", staleness)
+		}
+	default:
+		return nil, fmt.Errorf("unknown read consistency %v", req.Consistency)
+	}
+
 	value, found := s.store.Get(req.Key)
 	return &pb.GetResponse{Value: value, Found: found}, nil
 }
 
 func (s *DatabaseServer) Set(ctx context.Context, req *pb.SetRequest) (*pb.SetResponse, error) {
-	// Check if leader
-	if !s.raftNode.IsLeader() {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+
+	err := s.raftNode.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_PUT, Key: req.Key, Value: req.Value})
+	if err == raft.ErrNotLeader {
+		if client, ctx, ok := s.forwardTarget(ctx); ok {
+			resp, err := client.Set(ctx, req)
+			if err != nil {
+				return &pb.SetResponse{Success: false, Error: fmt.Sprintf("forward to leader: %v", err)}, nil
+			}
+			return resp, nil
+		}
+		leaderID, leaderAddr, leaderHTTPAddr := s.leaderHint()
 		return &pb.SetResponse{
-			Success:  false,
-			Error:    "Not Leader",
-			LeaderId: s.raftNode.LeaderID(),
+			Success:        false,
+			Error:          "Not Leader",
+			LeaderId:       leaderID,
+			LeaderAddr:     leaderAddr,
+			LeaderHttpAddr: leaderHTTPAddr,
 		}, nil
 	}
-
-	// TODO: Propose to Raft log instead of direct write
-	// For now, we write directly to show integration, but real Raft should apply via ApplyCh
-	s.store.Set(req.Key, req.Value)
+	if err != nil {
+		return &pb.SetResponse{Success: false, Error: err.Error()}, nil
+	}
 	return &pb.SetResponse{Success: true}, nil
 }
 
+func (s *DatabaseServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+
+	err := s.raftNode.Propose(ctx, &pb.LogEntry{Type: pb.EntryType_ENTRY_DELETE, Key: req.Key})
+	if err == raft.ErrNotLeader {
+		if client, ctx, ok := s.forwardTarget(ctx); ok {
+			resp, err := client.Delete(ctx, req)
+			if err != nil {
+				return &pb.DeleteResponse{Success: false, Error: fmt.Sprintf("forward to leader: %v", err)}, nil
+			}
+			return resp, nil
+		}
+		leaderID, leaderAddr, leaderHTTPAddr := s.leaderHint()
+		return &pb.DeleteResponse{
+			Success:        false,
+			Error:          "Not Leader",
+			LeaderId:       leaderID,
+			LeaderAddr:     leaderAddr,
+			LeaderHttpAddr: leaderHTTPAddr,
+		}, nil
+	}
+	if err != nil {
+		return &pb.DeleteResponse{Success: false, Error: err.Error()}, nil
+	}
+	return &pb.DeleteResponse{Success: true}, nil
+}
+
+// forwardTarget returns a client for the leader and the context to call it
+// with, if this follower should proxy the write rather than redirect the client.
+func (s *DatabaseServer) forwardTarget(ctx context.Context) (pb.DatabaseClient, context.Context, bool) {
+	if !s.cfg.ForwardWrites || isForwarded(ctx) {
+		return nil, nil, false
+	}
+
+	client, err := s.raftNode.LeaderClient()
+	if err != nil {
+		return nil, nil, false
+	}
+	return client, metadata.AppendToOutgoingContext(ctx, forwardedKey, s.raftNode.ID()), true
+}
+
+// isForwarded reports whether the request was already proxied by another node.
+func isForwarded(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(forwardedKey)) > 0
+}
+
+// leaderHint tells the client where to send its write instead.
+func (s *DatabaseServer) leaderHint() (id, addr, httpAddr string) {
+	id, addr = s.raftNode.Leader()
+	return id, addr, s.cfg.HTTPAddrs[id]
+}
+
 func (s *DatabaseServer) RequestVote(ctx context.Context, req *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
 	return s.raftNode.RequestVote(ctx, req)
 }
 
+func (s *DatabaseServer) PreVote(ctx context.Context, req *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	return s.raftNode.PreVote(ctx, req)
+}
+
 func (s *DatabaseServer) AppendEntries(ctx context.Context, req *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
 	return s.raftNode.AppendEntries(ctx, req)
 }
@@ -74,21 +289,96 @@ func (s *DatabaseServer) InstallSnapshot(ctx context.Context, req *pb.InstallSna
 	return s.raftNode.InstallSnapshot(ctx, req)
 }
 
+func (s *DatabaseServer) ReadIndex(ctx context.Context, req *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	return s.raftNode.ReadIndex(ctx, req)
+}
+
+func (s *DatabaseServer) TimeoutNow(ctx context.Context, req *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	return s.raftNode.TimeoutNow(ctx, req)
+}
+
 func (s *DatabaseServer) TakeSnapshot(ctx context.Context, req *pb.TakeSnapshotRequest) (*pb.TakeSnapshotResponse, error) {
 	if !s.raftNode.IsLeader() {
 		return &pb.TakeSnapshotResponse{Success: false}, fmt.Errorf("not leader")
 	}
 
-	// 1. Get snapshot from store
-	data, err := s.store.GetSnapshot()
-	if err != nil {
+	if err := s.snapshot(); err != nil {
 		return &pb.TakeSnapshotResponse{Success: false}, err
 	}
+	return &pb.TakeSnapshotResponse{Success: true}, nil
+}
 
-	// 2. Pass to RaftNode to persist (using dummy index 1 for POC)
-	s.raftNode.Snapshot(1, data)
+// AddMember adds a node to the cluster, as a voter or a learner. It must be
+// sent to the leader.
+func (s *DatabaseServer) AddMember(ctx context.Context, req *pb.AddMemberRequest) (*pb.MembershipResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+	if req.Learner {
+		return s.membershipResponse(s.raftNode.AddLearner(ctx, req.Id, req.Addr)), nil
+	}
+	return s.membershipResponse(s.raftNode.AddMember(ctx, req.Id, req.Addr)), nil
+}
 
-	return &pb.TakeSnapshotResponse{Success: true}, nil
+// PromoteMember makes a caught-up learner a voter. It must be sent to the leader.
+func (s *DatabaseServer) PromoteMember(ctx context.Context, req *pb.PromoteMemberRequest) (*pb.MembershipResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+	return s.membershipResponse(s.raftNode.PromoteMember(ctx, req.Id)), nil
+}
+
+// RemoveMember removes a node from the cluster. It must be sent to the leader.
+func (s *DatabaseServer) RemoveMember(ctx context.Context, req *pb.RemoveMemberRequest) (*pb.MembershipResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+	return s.membershipResponse(s.raftNode.RemoveMember(ctx, req.Id)), nil
+}
+
+// TransferLeadership hands leadership to another voter, e.g. before taking the
+// leader down for maintenance. It must be sent to the leader.
+func (s *DatabaseServer) TransferLeadership(ctx context.Context, req *pb.TransferLeadershipRequest) (*pb.TransferLeadershipResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, proposeTimeout)
+	defer cancel()
+
+	leaderID, err := s.raftNode.TransferLeadership(ctx, req.TargetId)
+	if err == raft.ErrNotLeader {
+		leaderID, leaderAddr, _ := s.leaderHint()
+		return &pb.TransferLeadershipResponse{Success: false, Error: "Not Leader", LeaderId: leaderID, LeaderAddr: leaderAddr}, nil
+	}
+	if err != nil {
+		return &pb.TransferLeadershipResponse{Success: false, Error: err.Error()}, nil
+	}
+	_, leaderAddr, _ := s.leaderHint()
+	return &pb.TransferLeadershipResponse{Success: true, LeaderId: leaderID, LeaderAddr: leaderAddr}, nil
+}
+
+// membershipResponse reports the outcome of a membership change.
+func (s *DatabaseServer) membershipResponse(err error) *pb.MembershipResponse {
+	if err == raft.ErrNotLeader {
+		leaderID, leaderAddr, _ := s.leaderHint()
+		return &pb.MembershipResponse{Success: false, Error: "Not Leader", LeaderId: leaderID, LeaderAddr: leaderAddr}
+	}
+	if err != nil {
+		return &pb.MembershipResponse{Success: false, Error: err.Error(), Members: s.raftNode.Members()}
+	}
+	return &pb.MembershipResponse{Success: true, Members: s.raftNode.Members()}
+}
+
+func (s *DatabaseServer) Status(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	st := s.raftNode.Status()
+	resp := &pb.StatusResponse{
+		Id:                st.ID,
+		State:             string(st.State),
+		Term:              int64(st.Term),
+		LeaderId:          st.LeaderID,
+		CommitIndex:       int64(st.CommitIndex),
+		LastApplied:       int64(st.LastApplied),
+		LastSnapshotIndex: int64(st.SnapshotIndex),
+		Members:           st.Members,
+	}
+	if !st.SnapshotTime.IsZero() {
+		resp.LastSnapshotTimeUnixMs = st.SnapshotTime.UnixMilli()
+	}
+	return resp, nil
 }
 
 func StartGRPCServer(addr string, srv *DatabaseServer) {
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"grassdb/internal/raft"
+	"grassdb/internal/storage"
+
+	pb "github.com/ranjan42/grassdb/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// startCluster starts a server for each of ids, talking Raft over a
+// MemNetwork, and stops them when the test ends. rcfg and cfg are the
+// settings shared by every node; IDs, addresses and directories are filled in.
+func startCluster(t *testing.T, rcfg raft.Config, cfg Config, ids ...string) (*raft.MemNetwork, []*DatabaseServer) {
+	network := raft.NewMemNetwork(1)
+	var servers []*DatabaseServer
+	for _, id := range ids {
+		rc := rcfg
+		rc.ID, rc.Addr, rc.Peers = id, id, make(map[string]string)
+		for _, p := range ids {
+			if p != id {
+				rc.Peers[p] = p
+			}
+		}
+		tr := network.Transport(id)
+		rc.Transport = tr
+		s := newTestServer(t, rc, cfg)
+		tr.Serve(s.raftNode)
+		servers = append(servers, s)
+	}
+	return network, servers
+}
+
+// startGRPCCluster starts a server for each of ids, serving gRPC on loopback
+// as they would in production, and stops them when the test ends.
+func startGRPCCluster(t *testing.T, cfg Config, ids ...string) []*DatabaseServer {
+	addrs := make(map[string]string)
+	listeners := make(map[string]net.Listener)
+	for _, id := range ids {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs[id], listeners[id] = lis.Addr().String(), lis
+	}
+
+	var servers []*DatabaseServer
+	for _, id := range ids {
+		rc := raft.Config{ID: id, Addr: addrs[id], Peers: make(map[string]string)}
+		for _, p := range ids {
+			if p != id {
+				rc.Peers[p] = addrs[p]
+			}
+		}
+		s := newTestServer(t, rc, cfg)
+		grpcServer := grpc.NewServer()
+		pb.RegisterDatabaseServer(grpcServer, s)
+		go grpcServer.Serve(listeners[id])
+		t.Cleanup(grpcServer.Stop)
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+// newTestServer starts a Raft node and a server on it, both keeping their
+// data in a temp dir, and stops them when the test ends.
+func newTestServer(t *testing.T, rc raft.Config, cfg Config) *DatabaseServer {
+	rc.DataDir = t.TempDir()
+	cfg.DataDir = rc.DataDir
+	applyCh := make(chan raft.ApplyMsg)
+	rn, err := raft.NewRaftNode(rc, applyCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rn.Stop)
+	s := NewServer(rn, applyCh, cfg)
+	// Let a background snapshot finish before its directory is removed
+	t.Cleanup(func() {
+		waitFor(t, "background snapshots to finish", func() bool { return !s.snapshotting.Load() })
+	})
+	return s
+}
+
+// waitFor fails the test unless cond holds within five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForLeader returns the leader once all of servers agree on it.
+func waitForLeader(t *testing.T, servers []*DatabaseServer) *DatabaseServer {
+	t.Helper()
+	var leader *DatabaseServer
+	waitFor(t, "a leader", func() bool {
+		leader = nil
+		for _, s := range servers {
+			if s.raftNode.IsLeader() {
+				leader = s
+			}
+		}
+		if leader == nil {
+			return false
+		}
+		for _, s := range servers {
+			if id, _ := s.raftNode.Leader(); id != leader.raftNode.ID() {
+				return false
+			}
+		}
+		return true
+	})
+	return leader
+}
+
+// storeContents returns everything in s's store, encoded as a snapshot.
+func storeContents(t *testing.T, s *DatabaseServer) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.store.GetSnapshot().Encode(&buf, storage.SnapshotMetadata{}, false); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWritesReachEveryStore(t *testing.T) {
+	_, servers := startCluster(t, raft.Config{}, Config{}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	ctx := context.Background()
+
+	// A write is applied to the leader's store by the time it is acknowledged
+	for i := 0; i < 20; i++ {
+		key, value := fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)
+		resp, err := leader.Set(ctx, &pb.SetRequest{Key: key, Value: value})
+		if err != nil || !resp.Success {
+			t.Fatalf("Set(%s): %v, %v", key, resp, err)
+		}
+		if got, ok := leader.store.Get(key); !ok || got != value {
+			t.Fatalf("after Set(%s), the leader's store holds %q, %v", key, got, ok)
+		}
+	}
+	resp, err := leader.Delete(ctx, &pb.DeleteRequest{Key: "k0"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Delete: %v, %v", resp, err)
+	}
+	if _, ok := leader.store.Get("k0"); ok {
+		t.Fatalf("after Delete, the leader's store still holds k0")
+	}
+
+	// The followers' stores end up the same, through committed entries alone
+	want := storeContents(t, leader)
+	for _, s := range servers {
+		waitFor(t, s.raftNode.ID()+"'s store to match the leader's", func() bool {
+			return bytes.Equal(storeContents(t, s), want)
+		})
+	}
+}
+
+// follower returns one of servers other than leader.
+func follower(servers []*DatabaseServer, leader *DatabaseServer) *DatabaseServer {
+	for _, s := range servers {
+		if s != leader {
+			return s
+		}
+	}
+	return nil
+}
+
+// postSet sends a write to s's HTTP /set handler.
+func postSet(t *testing.T, s *DatabaseServer, key, value string) *pb.SetResponse {
+	t.Helper()
+	body, err := json.Marshal(&pb.SetRequest{Key: key, Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	(&httpServer{db: s}).handleSet(rec, httptest.NewRequest(http.MethodPost, "/set", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/set returned %d: %s", rec.Code, rec.Body)
+	}
+	var resp pb.SetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func TestFollowerForwardsWrites(t *testing.T) {
+	httpAddrs := map[string]string{"n1": "n1:8080", "n2": "n2:8080", "n3": "n3:8080"}
+	servers := startGRPCCluster(t, Config{ForwardWrites: true, HTTPAddrs: httpAddrs}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	f := follower(servers, leader)
+	ctx := context.Background()
+
+	// Writes sent to a follower are made by the leader
+	resp, err := f.Set(ctx, &pb.SetRequest{Key: "grpc", Value: "v"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Set on a follower: %v, %v", resp, err)
+	}
+	if resp := postSet(t, f, "http", "v"); !resp.Success {
+		t.Fatalf("/set on a follower: %v", resp)
+	}
+	del, err := f.Delete(ctx, &pb.DeleteRequest{Key: "grpc"})
+	if err != nil || !del.Success {
+		t.Fatalf("Delete on a follower: %v, %v", del, err)
+	}
+	if _, ok := leader.store.Get("grpc"); ok {
+		t.Fatalf("forwarded delete has not been applied on the leader")
+	}
+	if v, ok := leader.store.Get("http"); !ok || v != "v" {
+		t.Fatalf("forwarded write has not been applied on the leader")
+	}
+
+	// A write that was forwarded once is never forwarded again, so the
+	// follower answers with where the leader is instead
+	leaderID := leader.raftNode.ID()
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedKey, "n9"))
+	resp, err = f.Set(ctx, &pb.SetRequest{Key: "loop", Value: "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || resp.LeaderId != leaderID || resp.LeaderHttpAddr != httpAddrs[leaderID] {
+		t.Fatalf("forwarded Set on a follower = %v, want a Not Leader reply pointing at %s", resp, leaderID)
+	}
+}
+
+func TestNotLeaderReply(t *testing.T) {
+	httpAddrs := map[string]string{"n1": "n1:8080", "n2": "n2:8080", "n3": "n3:8080"}
+	_, servers := startCluster(t, raft.Config{}, Config{HTTPAddrs: httpAddrs}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	f := follower(servers, leader)
+	leaderID := leader.raftNode.ID()
+
+	resp, err := f.Set(context.Background(), &pb.SetRequest{Key: "k", Value: "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || resp.Error != "Not Leader" || resp.LeaderId != leaderID || resp.LeaderAddr != leaderID || resp.LeaderHttpAddr != httpAddrs[leaderID] {
+		t.Fatalf("Set on a follower = %v, want a Not Leader reply pointing at %s", resp, leaderID)
+	}
+	if resp := postSet(t, f, "k", "v"); resp.Success || resp.LeaderHttpAddr != httpAddrs[leaderID] {
+		t.Fatalf("/set on a follower = %v, want leader_http_addr %s", resp, httpAddrs[leaderID])
+	}
+	if _, ok := f.store.Get("k"); ok {
+		t.Fatalf("follower wrote to its store without the leader")
+	}
+}
+
+func TestReadSeesAcknowledgedWrite(t *testing.T) {
+	_, servers := startCluster(t, raft.Config{}, Config{}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	ctx := context.Background()
+
+	// Each read, on any node, follows the write acknowledged just before it
+	for i := 0; i < 10; i++ {
+		value := fmt.Sprintf("v%d", i)
+		resp, err := leader.Set(ctx, &pb.SetRequest{Key: "k", Value: value})
+		if err != nil || !resp.Success {
+			t.Fatalf("Set: %v, %v", resp, err)
+		}
+		s := servers[i%len(servers)]
+		got, err := s.Get(ctx, &pb.GetRequest{Key: "k"})
+		if err != nil {
+			t.Fatalf("Get on %s: %v", s.raftNode.ID(), err)
+		}
+		if !got.Found || got.Value != value {
+			t.Fatalf("Get on %s after writing %q = %q, %v", s.raftNode.ID(), value, got.Value, got.Found)
+		}
+	}
+}
+
+func TestStaleReadMaxStaleness(t *testing.T) {
+	net, servers := startCluster(t, raft.Config{}, Config{}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	ctx := context.Background()
+	if resp, err := leader.Set(ctx, &pb.SetRequest{Key: "k", Value: "v"}); err != nil || !resp.Success {
+		t.Fatalf("Set: %v, %v", resp, err)
+	}
+	var fresh, cutOff *DatabaseServer
+	for _, s := range servers {
+		if s == leader {
+			continue
+		}
+		if cutOff == nil {
+			cutOff = s
+		} else {
+			fresh = s
+		}
+	}
+	stale := &pb.GetRequest{Key: "k", Consistency: pb.ReadConsistency_READ_STALE, MaxStalenessMs: 300}
+
+	// A follower that hears from the leader serves bounded stale reads
+	waitFor(t, "the write to reach the followers", func() bool {
+		_, ok := fresh.store.Get("k")
+		_, ok2 := cutOff.store.Get("k")
+		return ok && ok2
+	})
+	if resp, err := cutOff.Get(ctx, stale); err != nil || resp.Value != "v" {
+		t.Fatalf("stale read on a follower in contact = %v, %v", resp, err)
+	}
+
+	// Once it has lost contact for longer than the bound, it refuses them
+	net.Partition([]string{cutOff.raftNode.ID()})
+	waitFor(t, "the follower to go stale", func() bool { return cutOff.raftNode.Staleness() > 400*time.Millisecond })
+	if resp, err := cutOff.Get(ctx, stale); err == nil {
+		t.Fatalf("stale read on a follower out of contact = %v, want an error", resp)
+	}
+	rec := httptest.NewRecorder()
+	(&httpServer{db: cutOff}).handleGet(rec, httptest.NewRequest(http.MethodGet, "/get?key=k&consistency=stale&max_staleness=300ms", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("/get with max_staleness on a follower out of contact returned %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	// Without a bound it still answers from what it has
+	if resp, err := cutOff.Get(ctx, &pb.GetRequest{Key: "k", Consistency: pb.ReadConsistency_READ_STALE}); err != nil || resp.Value != "v" {
+		t.Fatalf("unbounded stale read = %v, %v", resp, err)
+	}
+	// The follower still in contact keeps serving them
+	if resp, err := fresh.Get(ctx, stale); err != nil || resp.Value != "v" {
+		t.Fatalf("stale read on a follower in contact = %v, %v", resp, err)
+	}
+	net.Heal()
+}
+
+// metrics returns what s's /metrics page reports.
+func metrics(t *testing.T, s *DatabaseServer) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	(&httpServer{db: s}).handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics returned %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsCountReadPaths(t *testing.T) {
+	_, servers := startCluster(t, raft.Config{LeaseReads: true}, Config{}, "n1", "n2", "n3")
+	leader := waitForLeader(t, servers)
+	f := follower(servers, leader)
+	ctx := context.Background()
+	for _, s := range []*DatabaseServer{leader, leader, f} {
+		if _, err := s.Get(ctx, &pb.GetRequest{Key: "k"}); err != nil {
+			t.Fatalf("Get on %s: %v", s.raftNode.ID(), err)
+		}
+	}
+	// Stale reads don't confirm leadership, so aren't counted
+	if _, err := f.Get(ctx, &pb.GetRequest{Key: "k", Consistency: pb.ReadConsistency_READ_STALE}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each read the leader served went one way or the other; the follower
+	// had to ask the leader
+	m := leader.raftNode.Metrics()
+	if m.LeaseReads+m.ReadIndexReads != 2 {
+		t.Fatalf("leader counted %+v, want 2 reads", m)
+	}
+	for _, tt := range []struct {
+		s                *DatabaseServer
+		lease, readIndex uint64
+	}{
+		{leader, m.LeaseReads, m.ReadIndexReads},
+		{f, 0, 1},
+	} {
+		got := metrics(t, tt.s)
+		for _, want := range []string{
+			fmt.Sprintf("grassdb_leadership_reads_total{path=\"lease\"} %d\n", tt.lease),
+			fmt.Sprintf("grassdb_leadership_reads_total{path=\"read_index\"} %d\n", tt.readIndex),
+		} {
+			if !strings.Contains(got, want) {
+				t.Fatalf("/metrics on %s = %q, want it to contain %q", tt.s.raftNode.ID(), got, want)
+			}
+		}
+	}
+}
+
+func TestAutomaticSnapshots(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		valueLen int
+		snapshot bool
+	}{
+		{"entry threshold", Config{SnapshotEntries: 10}, 10, true},
+		{"WAL byte threshold", Config{SnapshotWALBytes: 4 << 10}, 1 << 10, true},
+		{"thresholds not reached", Config{SnapshotEntries: 100, SnapshotWALBytes: 1 << 20}, 10, false},
+		{"disabled", Config{}, 1 << 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, servers := startCluster(t, raft.Config{}, tt.cfg, "n1")
+			s := waitForLeader(t, servers)
+			ctx := context.Background()
+			value := strings.Repeat("v", tt.valueLen)
+			for i := 0; i < 20; i++ {
+				if resp, err := s.Set(ctx, &pb.SetRequest{Key: fmt.Sprintf("k%d", i), Value: value}); err != nil || !resp.Success {
+					t.Fatalf("Set: %v, %v", resp, err)
+				}
+			}
+
+			if !tt.snapshot {
+				time.Sleep(200 * time.Millisecond)
+				if st, _ := s.Status(ctx, &pb.StatusRequest{}); st.LastSnapshotIndex != 0 {
+					t.Fatalf("snapshot taken at index %d below the thresholds", st.LastSnapshotIndex)
+				}
+				return
+			}
+			var st *pb.StatusResponse
+			waitFor(t, "an automatic snapshot", func() bool {
+				st, _ = s.Status(ctx, &pb.StatusRequest{})
+				return st.LastSnapshotIndex > 0
+			})
+			if st.LastSnapshotTimeUnixMs == 0 {
+				t.Fatalf("status reports a snapshot at index %d without its time", st.LastSnapshotIndex)
+			}
+			// The file on disk is the one Raft reports
+			f, err := os.Open(filepath.Join(s.cfg.DataDir, "distdb_n1.snap"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			d, err := storage.NewSnapshotDecoder(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.Metadata().LastIncludedIndex; int64(got) < st.LastSnapshotIndex {
+				t.Fatalf("snapshot file is at index %d, status reports %d", got, st.LastSnapshotIndex)
+			}
+		})
+	}
+}
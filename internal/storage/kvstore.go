@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"fmt"
+	"io"
+	"sort"
 	"sync"
 )
 
@@ -10,8 +13,11 @@ type Store struct {
 	wal  *WAL
 }
 
-func NewStoreWithWAL(path string) (*Store, error) {
-	wal, err := NewWAL(path)
+// NewStoreWithWAL opens the WAL at path and rebuilds the store from it. If
+// snapshot is not nil the store starts from the snapshot it reads, and the
+// WAL records after the snapshot's index are replayed on top.
+func NewStoreWithWAL(path string, snapshot io.Reader, cfg WALConfig) (*Store, error) {
+	wal, err := NewWAL(path, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -20,21 +26,63 @@ func NewStoreWithWAL(path string) (*Store, error) {
 		data: make(map[string]string),
 		wal:  wal,
 	}
-
-	if replayed, err := wal.Replay(); err == nil {
-		for k, v := range replayed {
-			s.data[k] = v
+	var snapshotIndex int
+	if snapshot != nil {
+		meta, data, err := decodeSnapshot(snapshot)
+		if err != nil {
+			wal.Close()
+			return nil, fmt.Errorf("restore snapshot: %w", err)
 		}
+		s.data, snapshotIndex = data, meta.LastIncludedIndex
+	}
+
+	if err := wal.Replay(s.data, snapshotIndex); err != nil {
+		wal.Close()
+		return nil, err
 	}
 
 	return s, nil
 }
 
-func (s *Store) Set(key, value string) {
+// Set stores value under key as the effect of the log entry at index. Entries
+// the WAL already holds are skipped, so replaying the Raft log after a restart
+// does not grow the WAL. Index 0 means the write did not come from a log.
+//
+// The write is visible to Get once it is in the WAL, and Set returns once the
+// WAL has synced it as its sync mode requires. The sync runs without holding
+// the store's lock, so reads don't wait on the disk and concurrent writers
+// share an fsync. If the WAL write fails the store is left unchanged.
+func (s *Store) Set(index int, key, value string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	_ = s.wal.Write(key, value) // In production: handle the error
+	if index > 0 && index <= s.wal.LastIndex() {
+		s.mu.Unlock()
+		return nil
+	}
+	seq, err := s.wal.append(recordPut, index, putPayload(key, value))
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
 	s.data[key] = value
+	s.mu.Unlock()
+	return s.wal.commit(seq)
+}
+
+// Delete removes key as the effect of the log entry at index; see Set.
+func (s *Store) Delete(index int, key string) error {
+	s.mu.Lock()
+	if index > 0 && index <= s.wal.LastIndex() {
+		s.mu.Unlock()
+		return nil
+	}
+	seq, err := s.wal.append(recordDelete, index, []byte(key))
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	delete(s.data, key)
+	s.mu.Unlock()
+	return s.wal.commit(seq)
 }
 
 func (s *Store) Get(key string) (string, bool) {
@@ -44,22 +92,89 @@ func (s *Store) Get(key string) (string, bool) {
 	return val, ok
 }
 
-// GetSnapshot returns the serialized state of the store.
-func (s *Store) GetSnapshot() ([]byte, error) {
+// StoreSnapshot is a point-in-time view of a Store that can be encoded
+// without holding the store's lock.
+type StoreSnapshot struct {
+	// Index is the index of the last log entry the view reflects.
+	Index   int
+	entries []snapshotEntry
+}
+
+// GetSnapshot captures the current state of the store. Keys and values are
+// shared with the store rather than copied, so writers are only held up for
+// as long as it takes to list them.
+func (s *Store) GetSnapshot() *StoreSnapshot {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return SerializeStore(s.data)
+	entries := make([]snapshotEntry, 0, len(s.data))
+	for k, v := range s.data {
+		entries = append(entries, snapshotEntry{k, v})
+	}
+	index := s.wal.LastIndex()
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return &StoreSnapshot{Index: index, entries: entries}
 }
 
-// RestoreFromSnapshot replaces the current state with the snapshot.
-func (s *Store) RestoreFromSnapshot(data []byte) error {
+// Encode writes the view to w in the snapshot format, labelled with meta.
+func (v *StoreSnapshot) Encode(w io.Writer, meta SnapshotMetadata, compress bool) error {
+	enc, err := NewSnapshotEncoder(w, meta, compress)
+	if err != nil {
+		return err
+	}
+	for _, e := range v.entries {
+		if err := enc.Write(e.key, e.value); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// LastIndex returns the index of the last log entry applied to the store.
+func (s *Store) LastIndex() int {
+	return s.wal.LastIndex()
+}
+
+// WALBytesWritten returns how many bytes the store has written to its WAL since it was opened.
+func (s *Store) WALBytesWritten() int64 {
+	return s.wal.BytesWritten()
+}
+
+// CompactWAL drops the WAL segments covered by a snapshot saved at index.
+func (s *Store) CompactWAL(index int) error {
+	return s.wal.Compact(index)
+}
+
+// RestoreFromSnapshot replaces the current state with the snapshot read
+// from r, such as one installed from the leader. The WAL is emptied, since
+// none of its records apply on top of the new state.
+func (s *Store) RestoreFromSnapshot(r io.Reader) error {
+	meta, data, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.data = data
+	return s.wal.Reset(meta.LastIncludedIndex)
+}
 
-	newState, err := DeserializeStore(data)
+// decodeSnapshot reads a whole snapshot into a map.
+func decodeSnapshot(r io.Reader) (SnapshotMetadata, map[string]string, error) {
+	d, err := NewSnapshotDecoder(r)
 	if err != nil {
-		return err
+		return SnapshotMetadata{}, nil, err
+	}
+	data := make(map[string]string)
+	for {
+		key, value, err := d.Next()
+		if err == io.EOF {
+			return d.Metadata(), data, nil
+		}
+		if err != nil {
+			return SnapshotMetadata{}, nil, err
+		}
+		data[key] = value
 	}
-	s.data = newState
-	return nil
 }
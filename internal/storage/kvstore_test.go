@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// op is a write applied to a Store in a test: a put, or a delete when del is set.
+type op struct {
+	index      int
+	key, value string
+	del        bool
+}
+
+func TestStoreReplaysTombstones(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []op
+		want map[string]string
+	}{
+		{"delete after put", []op{{1, "k", "v", false}, {2, "k", "", true}}, map[string]string{}},
+		{"put after delete", []op{{1, "k", "v1", false}, {2, "k", "", true}, {3, "k", "v2", false}}, map[string]string{"k": "v2"}},
+		{"delete leaves other keys", []op{{1, "a", "1", false}, {2, "b", "2", false}, {3, "a", "", true}}, map[string]string{"b": "2"}},
+		{"delete of a missing key", []op{{1, "k", "", true}}, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal")
+			s, err := NewStoreWithWAL(path, nil, WALConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range tt.ops {
+				var err error
+				if o.del {
+					err = s.Delete(o.index, o.key)
+				} else {
+					err = s.Set(o.index, o.key, o.value)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			s.wal.Close()
+
+			s, err = NewStoreWithWAL(path, nil, WALConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.wal.Close()
+			if len(s.data) != len(tt.want) {
+				t.Errorf("replayed %v, want %v", s.data, tt.want)
+			}
+			for k, v := range tt.want {
+				if got, ok := s.Get(k); !ok || got != v {
+					t.Errorf("Get(%q) = %q, %v; want %q", k, got, ok, v)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreReportsWALErrors(t *testing.T) {
+	s, err := NewStoreWithWAL(filepath.Join(t.TempDir(), "wal"), nil, WALConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(1, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	s.wal.Close()
+
+	// A write the WAL can't hold must not show up in the store
+	if err := s.Set(2, "k", "v2"); err == nil {
+		t.Fatalf("Set after the WAL closed succeeded")
+	}
+	if err := s.Delete(3, "k"); err == nil {
+		t.Fatalf("Delete after the WAL closed succeeded")
+	}
+	if got, _ := s.Get("k"); got != "v" {
+		t.Fatalf("Get(k) = %q, want %q", got, "v")
+	}
+}
+
+// BenchmarkStoreSet measures writes through the store in each sync mode. With
+// many writers in SyncAlways mode, fsyncs/op well below 1 shows them sharing
+// group commits.
+func BenchmarkStoreSet(b *testing.B) {
+	modes := []SyncMode{SyncAlways, SyncInterval, SyncNever}
+	value := string(make([]byte, 128))
+
+	for _, mode := range modes {
+		for _, parallelism := range []int{1, 64} {
+			b.Run(mode.String()+"/parallelism="+strconv.Itoa(parallelism), func(b *testing.B) {
+				s, err := NewStoreWithWAL(filepath.Join(b.TempDir(), "wal"), nil, WALConfig{SyncMode: mode})
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer s.wal.Close()
+
+				var index atomic.Int64
+				b.SetParallelism(parallelism)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						i := int(index.Add(1))
+						if err := s.Set(i, "key"+strconv.Itoa(i), value); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				})
+				b.StopTimer()
+				s.wal.syncMu.Lock()
+				b.ReportMetric(float64(s.wal.fsyncs)/float64(b.N), "fsyncs/op")
+				s.wal.syncMu.Unlock()
+			})
+		}
+	}
+}
@@ -1,41 +1,384 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 )
 
 // SnapshotMetadata holds info about the snapshot
 type SnapshotMetadata struct {
 	LastIncludedIndex int
 	LastIncludedTerm  int
+	// Config is the cluster configuration at LastIncludedIndex, encoded by Raft.
+	Config []byte
 	// Timestamp, etc.
 }
 
-// SaveSnapshot saves the snapshot data to a file.
-func SaveSnapshot(path string, data []byte) error {
-	// Write atomically: write to temp file then rename
-	tmpPath := path + ".tmp"
-	err := os.WriteFile(tmpPath, data, 0644)
-	if err != nil {
+// A snapshot is a fixed header followed by a body of key/value records in
+// ascending key order, optionally compressed with DEFLATE:
+//
+//	header: magic "GSNP" | version u16 | flags u16 | index u64 | term u64 |
+//	        len(config) u32 | config | crc32 u32 of the header before it
+//	record: 1 u8 | uvarint(len(key)) | key | uvarint(len(value)) | value
+//	end:    0 u8 | record count u64 | crc32 u32 of the uncompressed body before it
+//
+// Version 1 headers have no config.
+const (
+	snapshotMagic      = "GSNP"
+	snapshotVersion    = 2
+	snapshotHeaderSize = 24 // fixed part, before the config
+
+	snapshotCompressed = 1 << 0 // flag: the body is DEFLATE compressed
+
+	snapshotRecordKV  = 1
+	snapshotRecordEnd = 0
+
+	// maxSnapshotField bounds a key or value, so a corrupt length cannot
+	// trigger a huge allocation.
+	maxSnapshotField = 1 << 30
+	// maxSnapshotConfig bounds the config in the header likewise.
+	maxSnapshotConfig = 1 << 20
+)
+
+// ErrCorruptSnapshot is returned when a snapshot fails its checksums or is malformed.
+var ErrCorruptSnapshot = errors.New("snapshot: corrupt data")
+
+// SnapshotEncoder streams key/value records to a writer in the snapshot format.
+type SnapshotEncoder struct {
+	bw      *bufio.Writer
+	body    io.Writer
+	flate   *flate.Writer
+	crc     uint32
+	count   uint64
+	lastKey string
+	buf     []byte
+}
+
+// NewSnapshotEncoder writes the snapshot header for meta to w and returns an
+// encoder for its records. If compress is set the records are compressed.
+// Close must be called to complete the snapshot; it does not close w.
+func NewSnapshotEncoder(w io.Writer, meta SnapshotMetadata, compress bool) (*SnapshotEncoder, error) {
+	e := &SnapshotEncoder{bw: bufio.NewWriterSize(w, 64<<10)}
+
+	header := make([]byte, snapshotHeaderSize, snapshotHeaderSize+4+len(meta.Config)+4)
+	copy(header, snapshotMagic)
+	binary.BigEndian.PutUint16(header[4:6], snapshotVersion)
+	var flags uint16
+	if compress {
+		flags |= snapshotCompressed
+	}
+	binary.BigEndian.PutUint16(header[6:8], flags)
+	binary.BigEndian.PutUint64(header[8:16], uint64(meta.LastIncludedIndex))
+	binary.BigEndian.PutUint64(header[16:24], uint64(meta.LastIncludedTerm))
+	header = binary.BigEndian.AppendUint32(header, uint32(len(meta.Config)))
+	header = append(header, meta.Config...)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(header))
+	if _, err := e.bw.Write(header); err != nil {
+		return nil, err
+	}
+
+	e.body = e.bw
+	if compress {
+		fw, err := flate.NewWriter(e.bw, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		e.flate, e.body = fw, fw
+	}
+	return e, nil
+}
+
+// Write appends a record. Keys must be written in strictly ascending order.
+func (e *SnapshotEncoder) Write(key, value string) error {
+	if e.count > 0 && key <= e.lastKey {
+		return fmt.Errorf("snapshot: key %q written after %q", key, e.lastKey)
+	}
+	rec := append(e.buf[:0], snapshotRecordKV)
+	rec = binary.AppendUvarint(rec, uint64(len(key)))
+	rec = append(rec, key...)
+	rec = binary.AppendUvarint(rec, uint64(len(value)))
+	rec = append(rec, value...)
+	e.buf = rec
+
+	if _, err := e.body.Write(rec); err != nil {
 		return err
 	}
-	return os.Rename(tmpPath, path)
+	e.crc = crc32.Update(e.crc, crc32.IEEETable, rec)
+	e.count++
+	e.lastKey = key
+	return nil
 }
 
-// LoadSnapshot reads the snapshot data from a file.
-func LoadSnapshot(path string) ([]byte, error) {
-	return os.ReadFile(path)
+// Close writes the end record and flushes the snapshot to the underlying writer.
+func (e *SnapshotEncoder) Close() error {
+	end := []byte{snapshotRecordEnd}
+	end = binary.BigEndian.AppendUint64(end, e.count)
+	end = binary.BigEndian.AppendUint32(end, e.crc)
+	if _, err := e.body.Write(end); err != nil {
+		return err
+	}
+	if e.flate != nil {
+		if err := e.flate.Close(); err != nil {
+			return err
+		}
+	}
+	return e.bw.Flush()
 }
 
-// SerializeStore serializes the KV map to JSON.
-func SerializeStore(data map[string]string) ([]byte, error) {
-	return json.Marshal(data)
+// SnapshotDecoder reads key/value records from a snapshot, verifying its
+// checksums as it goes.
+type SnapshotDecoder struct {
+	meta  SnapshotMetadata
+	body  *bufio.Reader
+	crc   uint32
+	count uint64
+	done  bool
+
+	// legacy holds the records of a JSON snapshot written by older versions
+	legacy []snapshotEntry
 }
 
-// DeserializeStore deserializes JSON to a KV map.
-func DeserializeStore(data []byte) (map[string]string, error) {
-	var storedData map[string]string
-	err := json.Unmarshal(data, &storedData)
-	return storedData, err
+type snapshotEntry struct {
+	key, value string
+}
+
+// NewSnapshotDecoder reads the snapshot header from r. Records are then read
+// with Next. Snapshots written by older versions as JSON are also accepted;
+// those are read into memory in full.
+func NewSnapshotDecoder(r io.Reader) (*SnapshotDecoder, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	magic, err := br.Peek(len(snapshotMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if string(magic) != snapshotMagic {
+		return decodeLegacySnapshot(br)
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: short header", ErrCorruptSnapshot)
+	}
+	version := binary.BigEndian.Uint16(header[4:6])
+	var config []byte
+	switch version {
+	case 1:
+	case snapshotVersion:
+		var n [4]byte
+		if _, err := io.ReadFull(br, n[:]); err != nil {
+			return nil, fmt.Errorf("%w: short header", ErrCorruptSnapshot)
+		}
+		size := binary.BigEndian.Uint32(n[:])
+		if size > maxSnapshotConfig {
+			return nil, fmt.Errorf("%w: config of %d bytes", ErrCorruptSnapshot, size)
+		}
+		config = make([]byte, size)
+		if _, err := io.ReadFull(br, config); err != nil {
+			return nil, fmt.Errorf("%w: short header", ErrCorruptSnapshot)
+		}
+		header = append(append(header, n[:]...), config...)
+	default:
+		return nil, fmt.Errorf("snapshot: unsupported version %d", version)
+	}
+	var crc [4]byte
+	if _, err := io.ReadFull(br, crc[:]); err != nil {
+		return nil, fmt.Errorf("%w: short header", ErrCorruptSnapshot)
+	}
+	if crc32.ChecksumIEEE(header) != binary.BigEndian.Uint32(crc[:]) {
+		return nil, fmt.Errorf("%w: header checksum mismatch", ErrCorruptSnapshot)
+	}
+	flags := binary.BigEndian.Uint16(header[6:8])
+	if flags&^snapshotCompressed != 0 {
+		return nil, fmt.Errorf("snapshot: unsupported flags %#x", flags)
+	}
+
+	d := &SnapshotDecoder{
+		meta: SnapshotMetadata{
+			LastIncludedIndex: int(binary.BigEndian.Uint64(header[8:16])),
+			LastIncludedTerm:  int(binary.BigEndian.Uint64(header[16:24])),
+			Config:            config,
+		},
+		body: br,
+	}
+	if flags&snapshotCompressed != 0 {
+		d.body = bufio.NewReaderSize(flate.NewReader(br), 64<<10)
+	}
+	return d, nil
+}
+
+// decodeLegacySnapshot reads a JSON snapshot: the store's map, optionally
+// preceded by a line of JSON metadata.
+func decodeLegacySnapshot(r io.Reader) (*SnapshotDecoder, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &SnapshotDecoder{}
+	header, data, ok := bytes.Cut(buf, []byte{'\n'})
+	if !ok {
+		// The data alone, which never contains a raw newline
+		data = buf
+	} else if err := json.Unmarshal(header, &d.meta); err != nil {
+		return nil, fmt.Errorf("snapshot metadata: %w", err)
+	}
+
+	var kv map[string]string
+	if err := json.Unmarshal(data, &kv); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+	}
+	d.legacy = make([]snapshotEntry, 0, len(kv))
+	for k, v := range kv {
+		d.legacy = append(d.legacy, snapshotEntry{k, v})
+	}
+	sort.Slice(d.legacy, func(i, j int) bool { return d.legacy[i].key < d.legacy[j].key })
+	return d, nil
+}
+
+// Metadata returns the index and term the snapshot was taken at.
+func (d *SnapshotDecoder) Metadata() SnapshotMetadata {
+	return d.meta
+}
+
+// Next returns the next record in key order. It returns io.EOF once every
+// record has been read and the snapshot's checksum verified.
+func (d *SnapshotDecoder) Next() (key, value string, err error) {
+	if d.done {
+		return "", "", io.EOF
+	}
+	if d.body == nil {
+		if len(d.legacy) == 0 {
+			d.done = true
+			return "", "", io.EOF
+		}
+		e := d.legacy[0]
+		d.legacy = d.legacy[1:]
+		return e.key, e.value, nil
+	}
+
+	typ, err := d.body.ReadByte()
+	if err != nil {
+		return "", "", truncated(err)
+	}
+	switch typ {
+	case snapshotRecordKV:
+	case snapshotRecordEnd:
+		var end [12]byte
+		if _, err := io.ReadFull(d.body, end[:]); err != nil {
+			return "", "", truncated(err)
+		}
+		if binary.BigEndian.Uint64(end[:8]) != d.count || binary.BigEndian.Uint32(end[8:]) != d.crc {
+			return "", "", fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+		}
+		d.done = true
+		return "", "", io.EOF
+	default:
+		return "", "", fmt.Errorf("%w: unknown record type %d", ErrCorruptSnapshot, typ)
+	}
+
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, []byte{typ})
+	if key, err = d.readField(); err != nil {
+		return "", "", err
+	}
+	if value, err = d.readField(); err != nil {
+		return "", "", err
+	}
+	d.count++
+	return key, value, nil
+}
+
+// readField reads a length-prefixed string from the body.
+func (d *SnapshotDecoder) readField() (string, error) {
+	n, err := binary.ReadUvarint(d.body)
+	if err != nil {
+		return "", truncated(err)
+	}
+	if n > maxSnapshotField {
+		return "", fmt.Errorf("%w: field of %d bytes", ErrCorruptSnapshot, n)
+	}
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, binary.AppendUvarint(nil, n))
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.body, buf); err != nil {
+		return "", truncated(err)
+	}
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, buf)
+	return string(buf), nil
+}
+
+// truncated reports a snapshot that ends before its end record as corrupt.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	return err
+}
+
+// SaveSnapshot atomically replaces the file at path with the snapshot that
+// write produces, syncing it to disk.
+func SaveSnapshot(path string, write func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// ReadSnapshotMetadata reads the metadata from the snapshot file at path.
+func ReadSnapshotMetadata(path string) (SnapshotMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SnapshotMetadata{}, err
+	}
+	defer f.Close()
+	d, err := NewSnapshotDecoder(f)
+	if err != nil {
+		return SnapshotMetadata{}, err
+	}
+	return d.Metadata(), nil
+}
+
+// VerifySnapshot reads the whole snapshot file at path, checking its
+// checksums, and returns its metadata.
+func VerifySnapshot(path string) (SnapshotMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SnapshotMetadata{}, err
+	}
+	defer f.Close()
+	d, err := NewSnapshotDecoder(f)
+	if err != nil {
+		return SnapshotMetadata{}, err
+	}
+	for {
+		if _, _, err := d.Next(); err == io.EOF {
+			return d.Metadata(), nil
+		} else if err != nil {
+			return SnapshotMetadata{}, err
+		}
+	}
 }
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	meta := SnapshotMetadata{LastIncludedIndex: 42, LastIncludedTerm: 7, Config: []byte(`{"voters":["n1"]}`)}
+	tests := []struct {
+		name     string
+		data     map[string]string
+		compress bool
+	}{
+		{"empty", map[string]string{}, false},
+		{"empty compressed", map[string]string{}, true},
+		{"records", map[string]string{"a": "1", "b=c": "d\ne", "\x00": ""}, false},
+		{"records compressed", map[string]string{"a": "1", "b=c": "d\ne", "\x00": ""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := encodeSnapshot(t, tt.data, meta, tt.compress)
+			d, err := NewSnapshotDecoder(bytes.NewReader(snap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := d.Metadata()
+			if got.LastIncludedIndex != meta.LastIncludedIndex || got.LastIncludedTerm != meta.LastIncludedTerm || !bytes.Equal(got.Config, meta.Config) {
+				t.Fatalf("Metadata = %+v, want %+v", got, meta)
+			}
+			data, err := readSnapshot(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(data) != fmt.Sprint(tt.data) {
+				t.Fatalf("decoded %q, want %q", data, tt.data)
+			}
+		})
+	}
+}
+
+func TestSnapshotEncoderRejectsOutOfOrderKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"descending", []string{"b", "a"}},
+		{"duplicate", []string{"a", "a"}},
+		{"empty key after another", []string{"a", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewSnapshotEncoder(io.Discard, SnapshotMetadata{}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := len(tt.keys) - 1
+			for _, k := range tt.keys[:last] {
+				if err := e.Write(k, "v"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := e.Write(tt.keys[last], "v"); err == nil {
+				t.Fatalf("Write(%q) after %q succeeded", tt.keys[last], tt.keys[last-1])
+			}
+		})
+	}
+}
+
+func TestSnapshotDecoderDetectsDamage(t *testing.T) {
+	meta := SnapshotMetadata{LastIncludedIndex: 3, LastIncludedTerm: 1}
+	data := map[string]string{"a": "1", "b": "2", "c": "3"}
+	// Fixed header, config length and header checksum, with no config
+	headerSize := snapshotHeaderSize + 8
+
+	tests := []struct {
+		name     string
+		compress bool
+		damage   func(snap []byte) []byte
+	}{
+		{"bad header checksum", false, func(snap []byte) []byte {
+			snap[headerSize-1] ^= 0xff
+			return snap
+		}},
+		{"bad header field", false, func(snap []byte) []byte {
+			snap[10] ^= 0xff // In the index
+			return snap
+		}},
+		{"bad body checksum", false, func(snap []byte) []byte {
+			snap[len(snap)-1] ^= 0xff
+			return snap
+		}},
+		{"bad record", false, func(snap []byte) []byte {
+			snap[headerSize+2] ^= 0x01 // The first key, still in order
+			return snap
+		}},
+		{"bad record count", false, func(snap []byte) []byte {
+			snap[len(snap)-5] ^= 0xff
+			return snap
+		}},
+		{"truncated header", false, func(snap []byte) []byte { return snap[:headerSize-2] }},
+		{"truncated body", false, func(snap []byte) []byte { return snap[:len(snap)-6] }},
+		{"missing end record", false, func(snap []byte) []byte { return snap[:len(snap)-13] }},
+		{"truncated compressed body", true, func(snap []byte) []byte { return snap[:len(snap)-4] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := tt.damage(encodeSnapshot(t, data, meta, tt.compress))
+
+			d, err := NewSnapshotDecoder(bytes.NewReader(snap))
+			if err == nil {
+				_, err = readSnapshot(d)
+			}
+			if !errors.Is(err, ErrCorruptSnapshot) {
+				t.Fatalf("decode error = %v, want %v", err, ErrCorruptSnapshot)
+			}
+		})
+	}
+}
+
+func TestSnapshotDecoderReadsLegacyJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		snap      string
+		wantIndex int
+		want      map[string]string
+		wantErr   error
+	}{
+		{"data only", `{"a":"1","b":"x\ny"}`, 0, map[string]string{"a": "1", "b": "x\ny"}, nil},
+		{"with metadata", "{\"LastIncludedIndex\":9,\"LastIncludedTerm\":2}\n{\"a\":\"1\"}", 9, map[string]string{"a": "1"}, nil},
+		{"empty map", `{}`, 0, map[string]string{}, nil},
+		{"malformed data", `{"a":`, 0, nil, ErrCorruptSnapshot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewSnapshotDecoder(bytes.NewReader([]byte(tt.snap)))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NewSnapshotDecoder error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.Metadata().LastIncludedIndex; got != tt.wantIndex {
+				t.Fatalf("LastIncludedIndex = %d, want %d", got, tt.wantIndex)
+			}
+			data, err := readSnapshot(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(data) != fmt.Sprint(tt.want) {
+				t.Fatalf("decoded %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+// encodeSnapshot returns data encoded as a snapshot with meta.
+func encodeSnapshot(t *testing.T, data map[string]string, meta SnapshotMetadata, compress bool) []byte {
+	t.Helper()
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	e, err := NewSnapshotEncoder(&buf, meta, compress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if err := e.Write(k, data[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readSnapshot reads every record from d, checking they come in key order.
+func readSnapshot(d *SnapshotDecoder) (map[string]string, error) {
+	data := make(map[string]string)
+	var last string
+	for {
+		k, v, err := d.Next()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 && k <= last {
+			return nil, fmt.Errorf("key %q read after %q", k, last)
+		}
+		data[k], last = v, k
+	}
+}
@@ -2,52 +2,727 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
+// A WAL is a directory of segment files named <seq>-<firstIndex>.wal, both as
+// 16 hex digits. seq orders the segments; firstIndex is a lower bound on the
+// log index of every record in the segment, so a segment holds nothing newer
+// than the next segment's firstIndex-1. Only the last segment is appended to.
+//
+// Segment format (version 2):
+//
+//	header: magic "GWAL" | version uint16 | reserved uint16
+//	record: type uint8 | length uint32 | payload | crc32 uint32
+//
+// The CRC covers type, length and payload. A put payload is
+// uvarint(index) | uvarint(len(key)) | key | value; a delete payload is
+// uvarint(index) | key. All fixed-size integers are big-endian.
+const (
+	walMagic   = "GWAL"
+	walVersion = 2
+
+	walHeaderSize    = 8
+	recordHeaderSize = 5 // type + length
+	recordCRCSize    = 4
+
+	recordPut    byte = 1
+	recordDelete byte = 2
+
+	segmentExt = ".wal"
+)
+
+// DefaultSegmentSize is the size at which the WAL starts a new segment when
+// WALConfig.SegmentSize is unset.
+const DefaultSegmentSize = 64 << 20
+
+// ErrCorruptWAL is returned by Replay when a damaged record is followed by more
+// data, meaning the damage is not simply a write torn by a crash.
+var ErrCorruptWAL = errors.New("wal: corrupt record")
+
+// SyncMode controls when WAL writes are fsynced to disk.
+type SyncMode int
+
+const (
+	// SyncAlways fsyncs before every write returns. Concurrent writers are
+	// group committed, sharing a single fsync.
+	SyncAlways SyncMode = iota
+	// SyncInterval fsyncs in the background every WALConfig.SyncInterval, so
+	// a crash can lose writes made since the last sync.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// DefaultSyncInterval is used by SyncInterval when no interval is configured.
+const DefaultSyncInterval = 10 * time.Millisecond
+
+func (m SyncMode) String() string {
+	switch m {
+	case SyncAlways:
+		return "always"
+	case SyncInterval:
+		return "interval"
+	case SyncNever:
+		return "never"
+	}
+	return fmt.Sprintf("SyncMode(%d)", int(m))
+}
+
+// ParseSyncMode parses "always", "interval" or "never".
+func ParseSyncMode(s string) (SyncMode, error) {
+	for _, m := range []SyncMode{SyncAlways, SyncInterval, SyncNever} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown WAL sync mode %q (want always, interval or never)", s)
+}
+
+// WALConfig holds the durability settings for a WAL. The zero value fsyncs
+// every write and rotates segments at DefaultSegmentSize.
+type WALConfig struct {
+	SyncMode     SyncMode
+	SyncInterval time.Duration
+	// SegmentSize is the size in bytes past which writes go to a new segment.
+	SegmentSize int64
+}
+
+type segment struct {
+	seq        uint64
+	firstIndex int
+}
+
+func (s segment) name() string {
+	return fmt.Sprintf("%016x-%016x%s", s.seq, s.firstIndex, segmentExt)
+}
+
 type WAL struct {
-	file *os.File
-	mu   sync.Mutex
+	dir string
+	cfg WALConfig
+
+	mu        sync.Mutex
+	segments  []segment // oldest first; the last one is open for appends
+	file      *os.File
+	size      int64 // bytes in the open segment
+	lastIndex int   // highest index written or replayed
+
+	// written counts records handed to the file; synced is the count known
+	// to be on disk. Writers waiting on syncMu find their record already
+	// covered by whichever fsync ran while they queued.
+	written uint64
+	syncMu  sync.Mutex
+	synced  uint64
+	fsyncs  uint64 // fsyncs issued by syncTo, guarded by syncMu
+
+	bytesWritten int64 // bytes appended since the WAL was opened
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWAL opens the WAL in dir, creating it if needed. A single-file WAL left
+// at dir by an older version is converted into the first segment.
+func NewWAL(dir string, cfg WALConfig) (*WAL, error) {
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultSegmentSize
+	}
+	if cfg.SyncMode == SyncInterval && cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = DefaultSyncInterval
+	}
+	if err := migrateLegacyWAL(dir); err != nil {
+		return nil, fmt.Errorf("migrate WAL: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{dir: dir, cfg: cfg}
+	if len(segments) == 0 {
+		err = w.createSegment(segment{seq: 1, firstIndex: 1})
+	} else {
+		w.segments = segments
+		err = w.openSegment(segments[len(segments)-1])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SyncMode == SyncInterval {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.runSyncer()
+	}
+	return w, nil
 }
 
-func NewWAL(path string) (*WAL, error) {
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+// listSegments returns the segment files in dir ordered by sequence number.
+func listSegments(dir string) ([]segment, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
 	if err != nil {
 		return nil, err
 	}
-	return &WAL{file: file}, nil
+	var segments []segment
+	for _, name := range names {
+		var seg segment
+		if _, err := fmt.Sscanf(filepath.Base(name), "%016x-%016x"+segmentExt, &seg.seq, &seg.firstIndex); err != nil {
+			continue // Not a segment
+		}
+		segments = append(segments, seg)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+	return segments, nil
+}
+
+// createSegment starts a new, empty segment and makes it the one appended to.
+// Caller must hold w.mu, or be the constructor.
+func (w *WAL) createSegment(seg segment) error {
+	if err := writeFileSync(filepath.Join(w.dir, seg.name()), walHeader()); err != nil {
+		return err
+	}
+	w.segments = append(w.segments, seg)
+	return w.openSegment(seg)
 }
 
-func (w *WAL) Write(key, value string) error {
+// openSegment opens seg for appending after checking its header.
+func (w *WAL) openSegment(seg segment) error {
+	file, err := os.OpenFile(filepath.Join(w.dir, seg.name()), os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if err := checkHeader(file); err != nil {
+		file.Close()
+		return fmt.Errorf("segment %s: %w", seg.name(), err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	if seg.firstIndex-1 > w.lastIndex {
+		w.lastIndex = seg.firstIndex - 1
+	}
+	return nil
+}
+
+func checkHeader(file *os.File) error {
+	header := make([]byte, walHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("%w: truncated header", ErrCorruptWAL)
+	}
+	if string(header[:4]) != walMagic {
+		return fmt.Errorf("%w: bad magic", ErrCorruptWAL)
+	}
+	if v := binary.BigEndian.Uint16(header[4:6]); v != walVersion {
+		return fmt.Errorf("wal: unsupported version %d", v)
+	}
+	return nil
+}
+
+// Write records that key was set to value by the entry at index.
+func (w *WAL) Write(index int, key, value string) error {
+	seq, err := w.append(recordPut, index, putPayload(key, value))
+	if err != nil {
+		return err
+	}
+	return w.commit(seq)
+}
+
+// Delete appends a tombstone so that replay forgets the key.
+func (w *WAL) Delete(index int, key string) error {
+	seq, err := w.append(recordDelete, index, []byte(key))
+	if err != nil {
+		return err
+	}
+	return w.commit(seq)
+}
+
+// append writes a record without waiting for it to be synced and returns its
+// sequence number for commit.
+func (w *WAL) append(recType byte, index int, body []byte) (uint64, error) {
+	payload := binary.AppendUvarint(nil, uint64(index))
+	rec := encodeRecord(recType, append(payload, body...))
+
 	w.mu.Lock()
-	defer w.mu.Unlock()
-	_, err := fmt.Fprintf(w.file, "%s=%s\n", key, value)
-	return err
+	if w.size >= w.cfg.SegmentSize {
+		if err := w.rotate(); err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+	if _, err := w.file.Write(rec); err != nil {
+		w.mu.Unlock()
+		return 0, err
+	}
+	w.size += int64(len(rec))
+	w.bytesWritten += int64(len(rec))
+	if index > w.lastIndex {
+		w.lastIndex = index
+	}
+	w.written++
+	seq := w.written
+	w.mu.Unlock()
+	return seq, nil
 }
 
-func (w *WAL) Replay() (map[string]string, error) {
+// commit returns once record seq is as durable as the sync mode requires.
+func (w *WAL) commit(seq uint64) error {
+	if w.cfg.SyncMode == SyncAlways {
+		return w.syncTo(seq)
+	}
+	return nil
+}
+
+// rotate seals the open segment and starts the next one. The sealed segment
+// is synced regardless of the sync mode, so only the last segment can ever
+// end in a torn record. Caller must hold w.mu.
+func (w *WAL) rotate() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	last := w.segments[len(w.segments)-1]
+	return w.createSegment(segment{seq: last.seq + 1, firstIndex: w.lastIndex + 1})
+}
+
+// Sync flushes every record written so far to disk.
+func (w *WAL) Sync() error {
+	w.mu.Lock()
+	seq := w.written
+	w.mu.Unlock()
+	return w.syncTo(seq)
+}
+
+// syncTo returns once record seq is on disk. Only one fsync runs at a time;
+// it covers every record written before it started, so writers that queue
+// behind it are usually satisfied without issuing one of their own.
+func (w *WAL) syncTo(seq uint64) error {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+	if w.synced >= seq {
+		return nil
+	}
+
+	w.mu.Lock()
+	target := w.written
+	file := w.file
+	w.mu.Unlock()
+	// A segment closed by rotate was synced first, which covers target too
+	if err := file.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	w.fsyncs++
+	w.synced = target
+	return nil
+}
+
+// runSyncer fsyncs periodically in SyncInterval mode.
+func (w *WAL) runSyncer() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Sync(); err != nil {
+				log.Printf("WAL: background sync failed: %v", err)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Replay applies the records in the WAL to data, oldest first, skipping those
+// at or below after, the index of the snapshot data was loaded from. A torn
+// record at the tail of the last segment (left by a crash mid-write) is
+// truncated away; a damaged record anywhere else returns ErrCorruptWAL.
+func (w *WAL) Replay(data map[string]string, after int) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	_, err := w.file.Seek(0, 0)
+	for i, seg := range w.segments {
+		last := i == len(w.segments)-1
+		if err := w.replaySegment(seg, last, data, after); err != nil {
+			return fmt.Errorf("segment %s: %w", seg.name(), err)
+		}
+	}
+	return nil
+}
+
+// replaySegment applies the records of one segment. Caller must hold w.mu.
+func (w *WAL) replaySegment(seg segment, last bool, data map[string]string, after int) error {
+	f, err := os.Open(filepath.Join(w.dir, seg.name()))
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
 	}
+	size := info.Size()
 
-	scanner := bufio.NewScanner(w.file)
-	data := make(map[string]string)
-	for scanner.Scan() {
-		parts := strings.SplitN(scanner.Text(), "=", 2)
-		if len(parts) == 2 {
-			data[parts[0]] = parts[1]
+	if _, err := f.Seek(walHeaderSize, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+
+	offset := int64(walHeaderSize)
+	for offset < size {
+		recType, payload, n, err := readRecord(r, size-offset)
+		if err != nil {
+			tail := make([]byte, size-offset)
+			if _, err := f.ReadAt(tail, offset); err != nil {
+				return err
+			}
+			if isTornRecord(err, tail, n) && last {
+				log.Printf("WAL: discarding torn record at offset %d of %s", offset, seg.name())
+				if err := w.file.Truncate(offset); err != nil {
+					return err
+				}
+				w.size = offset
+				return nil
+			}
+			return damagedRecord(err, offset)
+		}
+
+		index, k := binary.Uvarint(payload)
+		if k <= 0 {
+			return fmt.Errorf("%w: bad index at offset %d", ErrCorruptWAL, offset)
+		}
+		if int(index) > w.lastIndex {
+			w.lastIndex = int(index)
+		}
+		offset += n
+		if after > 0 && int(index) <= after {
+			continue // Already in the snapshot
+		}
+
+		body := payload[k:]
+		switch recType {
+		case recordPut:
+			key, value, err := parsePutPayload(body)
+			if err != nil {
+				return fmt.Errorf("%w at offset %d", err, offset)
+			}
+			data[key] = value
+		case recordDelete:
+			delete(data, string(body))
+		}
+	}
+	return nil
+}
+
+// LastIndex returns the highest log index written to or replayed from the WAL.
+func (w *WAL) LastIndex() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastIndex
+}
+
+// BytesWritten returns how many bytes have been appended since the WAL was opened.
+func (w *WAL) BytesWritten() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bytesWritten
+}
+
+// Compact deletes the segments whose records all have an index at or below
+// index, typically the LastIncludedIndex of a snapshot that has been saved.
+// The open segment is never deleted.
+func (w *WAL) Compact(index int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.compact(index)
+}
+
+// Reset discards every record, for a store replaced by a snapshot taken at
+// index, and continues writing after it.
+func (w *WAL) Reset(index int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if index > w.lastIndex {
+		w.lastIndex = index
+	}
+	if err := w.rotate(); err != nil {
+		return err
+	}
+	return w.compact(w.lastIndex)
+}
+
+// compact implements Compact. Caller must hold w.mu.
+func (w *WAL) compact(index int) error {
+	removed := 0
+	for len(w.segments) > 1 && w.segments[1].firstIndex-1 <= index {
+		if err := os.Remove(filepath.Join(w.dir, w.segments[0].name())); err != nil {
+			return err
 		}
+		w.segments = w.segments[1:]
+		removed++
 	}
-	return data, scanner.Err()
+	if removed == 0 {
+		return nil
+	}
+	log.Printf("WAL: removed %d segments covered by index %d", removed, index)
+	return syncDir(w.dir)
 }
 
+// Close flushes any unsynced records and closes the open segment.
 func (w *WAL) Close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+	if err := w.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
 	return w.file.Close()
 }
+
+// migrateLegacyWAL converts a single-file WAL at path, in either the text
+// "key=value\n" format or the version 1 binary format, into a segment
+// directory at the same path. The file is first moved aside, so a crash
+// part way through is resumed on the next start. Legacy records carry no log
+// index and are recorded at index 0.
+func migrateLegacyWAL(path string) error {
+	legacyPath := path + ".legacy"
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		if err := os.Rename(path, legacyPath); err != nil {
+			return err
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	legacy, err := os.ReadFile(legacyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(walHeader())
+	records, err := convertLegacyWAL(legacy, buf)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	if err := writeFileSync(filepath.Join(path, segment{seq: 1}.name()), buf.Bytes()); err != nil {
+		return err
+	}
+	if err := os.Remove(legacyPath); err != nil {
+		return err
+	}
+	log.Printf("Migrated WAL %s to segmented format (%d records)", path, records)
+	return nil
+}
+
+// convertLegacyWAL writes the records of a legacy WAL to buf in the segment
+// format and returns how many there were.
+func convertLegacyWAL(legacy []byte, buf *bytes.Buffer) (int, error) {
+	records := 0
+
+	if len(legacy) >= walHeaderSize && string(legacy[:4]) == walMagic {
+		if v := binary.BigEndian.Uint16(legacy[4:6]); v != 1 {
+			return 0, fmt.Errorf("wal: unsupported version %d", v)
+		}
+		offset := int64(walHeaderSize)
+		size := int64(len(legacy))
+		r := bytes.NewReader(legacy[walHeaderSize:])
+		for offset < size {
+			recType, payload, n, err := readRecord(r, size-offset)
+			if err != nil {
+				if isTornRecord(err, legacy[offset:], n) {
+					break // Torn tail
+				}
+				return 0, damagedRecord(err, offset)
+			}
+			// Version 1 payloads are the version 2 ones without the index
+			buf.Write(encodeRecord(recType, legacyPayload(payload)))
+			records++
+			offset += n
+		}
+		return records, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(legacy))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Text WALs marked deletes with a NUL-prefixed line
+		if key, ok := strings.CutPrefix(line, "\x00"); ok {
+			buf.Write(encodeRecord(recordDelete, legacyPayload([]byte(key))))
+			records++
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			buf.Write(encodeRecord(recordPut, legacyPayload(putPayload(key, value))))
+			records++
+		}
+	}
+	return records, scanner.Err()
+}
+
+// legacyPayload prefixes a legacy record body with index 0.
+func legacyPayload(body []byte) []byte {
+	return append(binary.AppendUvarint(nil, 0), body...)
+}
+
+func walHeader() []byte {
+	header := make([]byte, walHeaderSize)
+	copy(header, walMagic)
+	binary.BigEndian.PutUint16(header[4:6], walVersion)
+	return header
+}
+
+func encodeRecord(recType byte, payload []byte) []byte {
+	rec := make([]byte, recordHeaderSize, recordHeaderSize+len(payload)+recordCRCSize)
+	rec[0] = recType
+	binary.BigEndian.PutUint32(rec[1:5], uint32(len(payload)))
+	rec = append(rec, payload...)
+	return binary.BigEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec))
+}
+
+// readRecord decodes the next record, which may use at most remaining bytes,
+// and returns its size on disk. Checksum and type errors report the size the
+// record claimed, so callers can tell whether it reached the end of the file.
+func readRecord(r io.Reader, remaining int64) (byte, []byte, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[1:5])
+	n := int64(recordHeaderSize) + int64(length) + recordCRCSize
+	if n > remaining {
+		return 0, nil, n, io.ErrUnexpectedEOF
+	}
+
+	body := make([]byte, int(length)+recordCRCSize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, n, err
+	}
+	payload := body[:length]
+	crc := crc32.ChecksumIEEE(header)
+	crc = crc32.Update(crc, crc32.IEEETable, payload)
+	if crc != binary.BigEndian.Uint32(body[length:]) {
+		return 0, nil, n, fmt.Errorf("%w: checksum mismatch", ErrCorruptWAL)
+	}
+	if header[0] != recordPut && header[0] != recordDelete {
+		return 0, nil, n, fmt.Errorf("%w: unknown record type %d", ErrCorruptWAL, header[0])
+	}
+	return header[0], payload, n, nil
+}
+
+// isTornRecord reports whether err, from reading the record of claimed size n
+// at the start of tail, is a write torn by a crash rather than corruption.
+// The record must run to the end of the file: a crash only cuts short the
+// last write. A corrupt length can make a record in the middle seem to run
+// past the end too, so an intact record ending the file after it means
+// corruption. Only a length reaching exactly to the end can start one, so
+// each offset costs one comparison and the checksum is rarely computed.
+func isTornRecord(err error, tail []byte, n int64) bool {
+	if !errors.Is(err, io.ErrUnexpectedEOF) && !(errors.Is(err, ErrCorruptWAL) && n >= int64(len(tail))) {
+		return false
+	}
+	for i := 1; i+recordHeaderSize+recordCRCSize <= len(tail); i++ {
+		rec := tail[i:]
+		end := len(rec) - recordCRCSize
+		if int(binary.BigEndian.Uint32(rec[1:5])) != end-recordHeaderSize || (rec[0] != recordPut && rec[0] != recordDelete) {
+			continue
+		}
+		if crc32.ChecksumIEEE(rec[:end]) == binary.BigEndian.Uint32(rec[end:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// damagedRecord reports a record at offset that could not be read and is not
+// a torn tail. One that seems to run past the end is corrupt too.
+func damagedRecord(err error, offset int64) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: record runs past the end at offset %d", ErrCorruptWAL, offset)
+	}
+	return fmt.Errorf("%w at offset %d", err, offset)
+}
+
+func putPayload(key, value string) []byte {
+	payload := binary.AppendUvarint(nil, uint64(len(key)))
+	payload = append(payload, key...)
+	return append(payload, value...)
+}
+
+func parsePutPayload(payload []byte) (string, string, error) {
+	keyLen, n := binary.Uvarint(payload)
+	if n <= 0 || uint64(len(payload)-n) < keyLen {
+		return "", "", fmt.Errorf("%w: bad put payload", ErrCorruptWAL)
+	}
+	key := payload[n : n+int(keyLen)]
+	return string(key), string(payload[n+int(keyLen):]), nil
+}
+
+// writeFileSync atomically replaces path with data, syncing both the file and its directory.
+func writeFileSync(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir fsyncs a directory so that renames and removals in it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWALRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		key, value string
+	}{
+		{"plain", "k", "v"},
+		{"equals signs", "a=b", "c=d=e"},
+		{"newlines", "line\nbreak", "two\nlines\n"},
+		{"empty value", "k", ""},
+		{"binary", "\x00\xff", "\x00\x01\x02"},
+		{"unicode", "ключ", "значение"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "wal")
+			w, err := NewWAL(dir, WALConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(1, "other", "x"); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(2, tt.key, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Delete(3, "other"); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+
+			data := replayWAL(t, dir)
+			if len(data) != 1 || data[tt.key] != tt.value {
+				t.Fatalf("replayed %q, want {%q: %q}", data, tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestWALDamagedRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(seg []byte, offsets []int64) []byte // offsets[i] is where record i+1 starts
+		want    int                                      // Records replayed
+		wantErr error
+	}{
+		{"torn record body", func(seg []byte, _ []int64) []byte { return seg[:len(seg)-2] }, 4, nil},
+		{"torn record header", func(seg []byte, offsets []int64) []byte { return seg[:offsets[4]+3] }, 4, nil},
+		{"garbage last record", func(seg []byte, _ []int64) []byte {
+			seg[len(seg)-1] ^= 0xff
+			return seg
+		}, 4, nil},
+		{"corrupt payload", func(seg []byte, offsets []int64) []byte {
+			seg[offsets[1]+recordHeaderSize+1] ^= 0xff
+			return seg
+		}, 0, ErrCorruptWAL},
+		{"corrupt length past the end", func(seg []byte, offsets []int64) []byte {
+			seg[offsets[1]+4] ^= 0xff
+			return seg
+		}, 0, ErrCorruptWAL},
+		{"corrupt length reaching the end", func(seg []byte, offsets []int64) []byte {
+			// The middle record seems to be the last one, torn
+			binary.BigEndian.PutUint32(seg[offsets[2]+1:], uint32(int64(len(seg))-offsets[2]-recordHeaderSize-recordCRCSize))
+			return seg
+		}, 0, ErrCorruptWAL},
+		{"corrupt length inside the segment", func(seg []byte, offsets []int64) []byte {
+			seg[offsets[1]+4]--
+			return seg
+		}, 0, ErrCorruptWAL},
+		{"unknown record type", func(seg []byte, offsets []int64) []byte {
+			seg[offsets[1]] = 9
+			return seg
+		}, 0, ErrCorruptWAL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "wal")
+			w, err := NewWAL(dir, WALConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var offsets []int64
+			for i := 1; i <= 5; i++ {
+				offsets = append(offsets, w.size)
+				if err := w.Write(i, "k"+strconv.Itoa(i), "v"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(dir, w.segments[0].name())
+			w.Close()
+
+			seg, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			damaged := tt.damage(seg, offsets)
+			if err := os.WriteFile(path, damaged, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			w, err = NewWAL(dir, WALConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			data := make(map[string]string)
+			err = w.Replay(data, 0)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Replay error = %v, want %v", err, tt.wantErr)
+				}
+				// Nothing is thrown away when the damage can't be a torn write
+				if info, err := os.Stat(path); err != nil || info.Size() != int64(len(damaged)) {
+					t.Fatalf("segment was changed after a corrupt record")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if len(data) != tt.want || w.LastIndex() != tt.want {
+				t.Fatalf("replayed %d records up to index %d, want %d", len(data), w.LastIndex(), tt.want)
+			}
+
+			// The torn record is gone, so the next write follows the last intact one
+			if err := w.Write(tt.want+1, "next", "v"); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			if data := replayWAL(t, dir); len(data) != tt.want+1 || data["next"] != "v" {
+				t.Fatalf("after appending, replayed %q", data)
+			}
+		})
+	}
+}
+
+func TestWALMigratesLegacyFormats(t *testing.T) {
+	v1Header := walHeader()
+	binary.BigEndian.PutUint16(v1Header[4:6], 1)
+	v1 := append(v1Header, encodeRecord(recordPut, putPayload("a", "1"))...)
+	v1 = append(v1, encodeRecord(recordPut, putPayload("b", "x=y\n"))...)
+	v1 = append(v1, encodeRecord(recordDelete, []byte("a"))...)
+
+	tests := []struct {
+		name   string
+		legacy []byte
+		want   map[string]string
+	}{
+		{"text", []byte("a=1\nb=x=y\n\x00a\n"), map[string]string{"b": "x=y"}},
+		{"text without trailing newline", []byte("a=1\nb=2"), map[string]string{"a": "1", "b": "2"}},
+		{"binary version 1", v1, map[string]string{"b": "x=y\n"}},
+		{"binary version 1 with a torn tail", v1[:len(v1)-3], map[string]string{"a": "1", "b": "x=y\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal")
+			if err := os.WriteFile(path, tt.legacy, 0644); err != nil {
+				t.Fatal(err)
+			}
+			data := replayWAL(t, path)
+			if len(data) != len(tt.want) {
+				t.Fatalf("replayed %q, want %q", data, tt.want)
+			}
+			for k, v := range tt.want {
+				if data[k] != v {
+					t.Fatalf("replayed %q, want %q", data, tt.want)
+				}
+			}
+			if _, err := os.Stat(path + ".legacy"); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("legacy file left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestWALSegments(t *testing.T) {
+	tests := []struct {
+		name         string
+		compact      func(w *WAL) error
+		wantSegments int // Segment files left on disk
+		wantFirst    int // Lowest index replayed, or 0 for none
+		wantLast     int // LastIndex afterwards
+	}{
+		{"rotation only", func(*WAL) error { return nil }, 11, 1, 10},
+		{"compact the empty first segment", func(w *WAL) error { return w.Compact(0) }, 10, 1, 10},
+		{"compact a prefix", func(w *WAL) error { return w.Compact(4) }, 6, 5, 10},
+		{"compact everything keeps the open segment", func(w *WAL) error { return w.Compact(100) }, 1, 10, 10},
+		{"reset", func(w *WAL) error { return w.Reset(20) }, 1, 0, 20},
+		{"reset behind the log", func(w *WAL) error { return w.Reset(3) }, 1, 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every write lands in a segment of its own
+			dir := filepath.Join(t.TempDir(), "wal")
+			w, err := NewWAL(dir, WALConfig{SegmentSize: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 10; i++ {
+				if err := w.Write(i, "k"+strconv.Itoa(i), "v"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.compact(w); err != nil {
+				t.Fatal(err)
+			}
+			open := w.segments[len(w.segments)-1]
+			if _, err := os.Stat(filepath.Join(dir, open.name())); err != nil {
+				t.Fatalf("open segment removed: %v", err)
+			}
+			if w.LastIndex() != tt.wantLast {
+				t.Fatalf("LastIndex = %d, want %d", w.LastIndex(), tt.wantLast)
+			}
+
+			// Writes carry on in the open segment and survive a restart
+			next := tt.wantLast + 1
+			if err := w.Write(next, "next", "v"); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			segments, err := listSegments(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) != tt.wantSegments+1 {
+				t.Fatalf("%d segments on disk, want %d", len(segments), tt.wantSegments+1)
+			}
+
+			data := replayWAL(t, dir)
+			want := 1
+			if tt.wantFirst > 0 {
+				want += 10 - tt.wantFirst + 1
+			}
+			if len(data) != want || data["next"] != "v" {
+				t.Fatalf("replayed %q, want %d keys", data, want)
+			}
+			for i := tt.wantFirst; tt.wantFirst > 0 && i <= 10; i++ {
+				if _, ok := data["k"+strconv.Itoa(i)]; !ok {
+					t.Fatalf("record %d lost: replayed %q", i, data)
+				}
+			}
+		})
+	}
+}
+
+// replayWAL opens the WAL in dir and returns the state it replays to.
+func replayWAL(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	w, err := NewWAL(dir, WALConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	data := make(map[string]string)
+	if err := w.Replay(data, 0); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return data
+}
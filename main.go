@@ -3,32 +3,73 @@ package main
 
 import (
 	"flag"
+	"log"
 	"strings"
 
 	"grassdb/internal/raft"
 	"grassdb/internal/server"
+	"grassdb/internal/storage"
 )
 
 func main() {
 	id := flag.String("id", "node1", "Unique node ID")
 	addr := flag.String("addr", ":50051", "Address to listen on for gRPC")
 	httpAddr := flag.String("http", ":8080", "Address to listen on for HTTP")
-	peersStr := flag.String("peers", "", "Comma-separated list of peer addresses (e.g. 127.0.0.1:50052,127.0.0.1:50053)")
+	peersStr := flag.String("peers", "", "Comma-separated list of peers as id=address (e.g. node2=127.0.0.1:50052,node3=127.0.0.1:50053) for the initial configuration")
+	join := flag.Bool("join", false, "Start without a configuration and wait to be added to an existing cluster with add-member")
+	peerHTTPStr := flag.String("peer-http", "", "Comma-separated list of peer HTTP addresses as id=address, used to redirect HTTP clients")
+	dataDir := flag.String("data-dir", ".", "Directory for the WAL, snapshots and Raft state")
+	forwardWrites := flag.Bool("forward-writes", false, "Proxy writes received by followers to the leader")
+	leaseReads := flag.Bool("lease-reads", false, "Serve linearizable reads from the leader lease instead of a ReadIndex round")
+	leaseDrift := flag.Duration("lease-drift", raft.DefaultLeaseDriftBound, "Clock drift bound subtracted from the leader lease")
+	walSync := flag.String("wal-sync", "always", "When to fsync the WAL: always, interval or never")
+	walSyncInterval := flag.Duration("wal-sync-interval", storage.DefaultSyncInterval, "How often to fsync the WAL with -wal-sync=interval")
+	snapshotEntries := flag.Int("snapshot-entries", 10000, "Take a snapshot after this many entries since the last one (0 disables)")
+	snapshotWALBytes := flag.Int64("snapshot-wal-bytes", 64<<20, "Take a snapshot after this many WAL bytes since the last one (0 disables)")
+	snapshotCompress := flag.Bool("snapshot-compress", false, "Compress snapshots with DEFLATE")
+	walSegmentSize := flag.Int64("wal-segment-size", storage.DefaultSegmentSize, "Size in bytes at which the WAL starts a new segment file")
 	flag.Parse()
 
-	var peers []string
-	if *peersStr != "" {
-		peers = strings.Split(*peersStr, ",")
+	syncMode, err := storage.ParseSyncMode(*walSync)
+	if err != nil {
+		log.Fatalf("invalid -wal-sync: %v", err)
 	}
 
+	peers := parsePeers(*peersStr)
+	httpAddrs := parsePeers(*peerHTTPStr)
+	httpAddrs[*id] = *httpAddr
+
 	// Channel to apply committed entries to the state machine
-	applyCh := make(chan string)
+	applyCh := make(chan raft.ApplyMsg)
 
 	// Initialize Raft Node
-	node := raft.NewRaftNode(*id, peers, applyCh)
+	node, err := raft.NewRaftNode(raft.Config{
+		ID:              *id,
+		Addr:            *addr,
+		Peers:           peers,
+		Join:            *join,
+		DataDir:         *dataDir,
+		LeaseReads:      *leaseReads,
+		LeaseDriftBound: *leaseDrift,
+	}, applyCh)
+	if err != nil {
+		log.Fatalf("failed to start raft node: %v", err)
+	}
 
 	// Initialize Database Server
-	dbServer := server.NewServer(node)
+	dbServer := server.NewServer(node, applyCh, server.Config{
+		DataDir:             *dataDir,
+		HTTPAddrs:           httpAddrs,
+		ForwardWrites:       *forwardWrites,
+		SnapshotEntries:     *snapshotEntries,
+		SnapshotWALBytes:    *snapshotWALBytes,
+		SnapshotCompression: *snapshotCompress,
+		WAL: storage.WALConfig{
+			SyncMode:     syncMode,
+			SyncInterval: *walSyncInterval,
+			SegmentSize:  *walSegmentSize,
+		},
+	})
 
 	// Start HTTP Server
 	go func() {
@@ -40,3 +81,20 @@ func main() {
 	// Start gRPC Server (handles both Database and Raft RPCs)
 	server.StartGRPCServer(*addr, dbServer)
 }
+
+// parsePeers parses a comma-separated list of id=address pairs. An entry
+// without an ID uses its address as the ID.
+func parsePeers(s string) map[string]string {
+	peers := make(map[string]string)
+	if s == "" {
+		return peers
+	}
+	for _, entry := range strings.Split(s, ",") {
+		id, addr, ok := strings.Cut(entry, "=")
+		if !ok {
+			addr = id
+		}
+		peers[id] = addr
+	}
+	return peers
+}
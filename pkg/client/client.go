@@ -20,35 +20,105 @@ func NewClient(peers []string) *Client {
 
 func (c *Client) Set(key, value string) error {
 	for _, peer := range c.peers {
-		conn, err := grpc.NewClient(peer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		resp, err := c.setOn(peer, key, value)
 		if err != nil {
-			continue // Try next peer
+			continue // RPC error (network, etc), try next
 		}
-		defer conn.Close()
+		if resp.Success {
+			return nil
+		}
+		if resp.Error != "Not Leader" {
+			return fmt.Errorf("server error: %s", resp.Error)
+		}
+		// Follow the redirect if the node knows who the leader is
+		if resp.LeaderAddr != "" {
+			if resp, err := c.setOn(resp.LeaderAddr, key, value); err == nil && resp.Success {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("failed to set key on any node")
+}
 
-		client := pb.NewDatabaseClient(conn)
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+// setOn sends a single Set RPC to the node at addr.
+func (c *Client) setOn(addr, key, value string) (*pb.SetResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
 
-		resp, err := client.Set(ctx, &pb.SetRequest{Key: key, Value: value})
-		if err == nil {
-			if !resp.Success {
-				// Not leader or other error, try next?
-				// Actually server returns Success=false if not leader.
-				// We should ideally check LeaderId redirect here.
-				if resp.Error == "Not Leader" {
-					continue
-				}
-				return fmt.Errorf("server error: %s", resp.Error)
+	client := pb.NewDatabaseClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return client.Set(ctx, &pb.SetRequest{Key: key, Value: value})
+}
+
+func (c *Client) Delete(key string) error {
+	for _, peer := range c.peers {
+		resp, err := c.deleteOn(peer, key)
+		if err != nil {
+			continue // RPC error (network, etc), try next
+		}
+		if resp.Success {
+			return nil
+		}
+		if resp.Error != "Not Leader" {
+			return fmt.Errorf("server error: %s", resp.Error)
+		}
+		// Follow the redirect if the node knows who the leader is
+		if resp.LeaderAddr != "" {
+			if resp, err := c.deleteOn(resp.LeaderAddr, key); err == nil && resp.Success {
+				return nil
 			}
-			return nil // Success
 		}
-		// RPC error (network, etc), try next
 	}
-	return fmt.Errorf("failed to set key on any node")
+	return fmt.Errorf("failed to delete key on any node")
+}
+
+// deleteOn sends a single Delete RPC to the node at addr.
+func (c *Client) deleteOn(addr, key string) (*pb.DeleteResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewDatabaseClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return client.Delete(ctx, &pb.DeleteRequest{Key: key})
+}
+
+// ReadOption configures the consistency of a Get.
+type ReadOption func(*pb.GetRequest)
+
+// Lease serves the read from the leader's local state while its lease holds,
+// avoiding a round of heartbeats.
+func Lease() ReadOption {
+	return func(req *pb.GetRequest) {
+		req.Consistency = pb.ReadConsistency_READ_LEASE
+	}
+}
+
+// Stale serves the read from any node's local state. If maxStaleness is
+// non-zero, nodes that haven't heard from the leader within it refuse the read.
+func Stale(maxStaleness time.Duration) ReadOption {
+	return func(req *pb.GetRequest) {
+		req.Consistency = pb.ReadConsistency_READ_STALE
+		req.MaxStalenessMs = maxStaleness.Milliseconds()
+	}
 }
 
-func (c *Client) Get(key string) (string, bool, error) {
+// Get reads a key. Reads are linearizable unless a ReadOption relaxes them.
+func (c *Client) Get(key string, opts ...ReadOption) (string, bool, error) {
+	req := &pb.GetRequest{Key: key}
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	for _, peer := range c.peers {
 		conn, err := grpc.NewClient(peer, grpc.WithTransportCredentials(insecure.NewCredentials()))
 		if err != nil {
@@ -60,7 +130,7 @@ func (c *Client) Get(key string) (string, bool, error) {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
 
-		resp, err := client.Get(ctx, &pb.GetRequest{Key: key})
+		resp, err := client.Get(ctx, req)
 		if err == nil {
 			return resp.Value, resp.Found, nil
 		}
@@ -88,3 +158,124 @@ func (c *Client) TakeSnapshot() error {
 	}
 	return fmt.Errorf("failed to take snapshot on any node")
 }
+
+// AddMember adds a node, reachable at addr, to the cluster and returns the
+// resulting configuration.
+func (c *Client) AddMember(id, addr string) ([]*pb.Member, error) {
+	return c.changeMembership(func(ctx context.Context, client pb.DatabaseClient) (*pb.MembershipResponse, error) {
+		return client.AddMember(ctx, &pb.AddMemberRequest{Id: id, Addr: addr})
+	})
+}
+
+// AddLearner adds a node, reachable at addr, to the cluster as a non-voting
+// learner and returns the resulting configuration.
+func (c *Client) AddLearner(id, addr string) ([]*pb.Member, error) {
+	return c.changeMembership(func(ctx context.Context, client pb.DatabaseClient) (*pb.MembershipResponse, error) {
+		return client.AddMember(ctx, &pb.AddMemberRequest{Id: id, Addr: addr, Learner: true})
+	})
+}
+
+// PromoteMember makes a learner that has caught up a voter and returns the
+// resulting configuration.
+func (c *Client) PromoteMember(id string) ([]*pb.Member, error) {
+	return c.changeMembership(func(ctx context.Context, client pb.DatabaseClient) (*pb.MembershipResponse, error) {
+		return client.PromoteMember(ctx, &pb.PromoteMemberRequest{Id: id})
+	})
+}
+
+// RemoveMember removes a node from the cluster and returns the resulting configuration.
+func (c *Client) RemoveMember(id string) ([]*pb.Member, error) {
+	return c.changeMembership(func(ctx context.Context, client pb.DatabaseClient) (*pb.MembershipResponse, error) {
+		return client.RemoveMember(ctx, &pb.RemoveMemberRequest{Id: id})
+	})
+}
+
+// changeMembership sends a membership change to the leader, following redirects.
+func (c *Client) changeMembership(send func(context.Context, pb.DatabaseClient) (*pb.MembershipResponse, error)) ([]*pb.Member, error) {
+	for _, peer := range c.peers {
+		resp, err := c.changeMembershipOn(peer, send)
+		if err != nil {
+			continue // RPC error (network, etc), try next
+		}
+		if resp.Error == "Not Leader" && resp.LeaderAddr != "" {
+			// Follow the redirect if the node knows who the leader is
+			if resp, err = c.changeMembershipOn(resp.LeaderAddr, send); err != nil {
+				continue
+			}
+		}
+		if resp.Success {
+			return resp.Members, nil
+		}
+		if resp.Error != "Not Leader" {
+			return nil, fmt.Errorf("server error: %s", resp.Error)
+		}
+	}
+	return nil, fmt.Errorf("failed to change membership on any node")
+}
+
+// changeMembershipOn sends a single membership change RPC to the node at addr.
+func (c *Client) changeMembershipOn(addr string, send func(context.Context, pb.DatabaseClient) (*pb.MembershipResponse, error)) (*pb.MembershipResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return send(ctx, pb.NewDatabaseClient(conn))
+}
+
+// TransferLeadership asks the leader to hand leadership to the node with ID
+// target, and returns the ID of the new leader.
+func (c *Client) TransferLeadership(target string) (string, error) {
+	for _, peer := range c.peers {
+		resp, err := c.transferLeadershipOn(peer, target)
+		if err != nil {
+			continue // RPC error (network, etc), try next
+		}
+		if resp.Error == "Not Leader" && resp.LeaderAddr != "" {
+			// Follow the redirect if the node knows who the leader is
+			if resp, err = c.transferLeadershipOn(resp.LeaderAddr, target); err != nil {
+				continue
+			}
+		}
+		if resp.Success {
+			return resp.LeaderId, nil
+		}
+		if resp.Error != "Not Leader" {
+			return "", fmt.Errorf("server error: %s", resp.Error)
+		}
+	}
+	return "", fmt.Errorf("failed to transfer leadership on any node")
+}
+
+// transferLeadershipOn sends a single TransferLeadership RPC to the node at addr.
+func (c *Client) transferLeadershipOn(addr, target string) (*pb.TransferLeadershipResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewDatabaseClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return client.TransferLeadership(ctx, &pb.TransferLeadershipRequest{TargetId: target})
+}
+
+// Status returns the Raft and snapshot status of the node at addr.
+func (c *Client) Status(addr string) (*pb.StatusResponse, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewDatabaseClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return client.Status(ctx, &pb.StatusRequest{})
+}
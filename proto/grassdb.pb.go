@@ -21,6 +21,107 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ReadConsistency int32
+
+const (
+	ReadConsistency_READ_LINEARIZABLE ReadConsistency = 0 // Confirmed with the leader via ReadIndex (default)
+	ReadConsistency_READ_LEASE        ReadConsistency = 1 // Served by the leader from local state while its lease holds
+	ReadConsistency_READ_STALE        ReadConsistency = 2 // Served from any node's local state
+)
+
+// Enum value maps for ReadConsistency.
+var (
+	ReadConsistency_name = map[int32]string{
+		0: "READ_LINEARIZABLE",
+		1: "READ_LEASE",
+		2: "READ_STALE",
+	}
+	ReadConsistency_value = map[string]int32{
+		"READ_LINEARIZABLE": 0,
+		"READ_LEASE":        1,
+		"READ_STALE":        2,
+	}
+)
+
+func (x ReadConsistency) Enum() *ReadConsistency {
+	p := new(ReadConsistency)
+	*p = x
+	return p
+}
+
+func (x ReadConsistency) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReadConsistency) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_grassdb_proto_enumTypes[0].Descriptor()
+}
+
+func (ReadConsistency) Type() protoreflect.EnumType {
+	return &file_proto_grassdb_proto_enumTypes[0]
+}
+
+func (x ReadConsistency) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReadConsistency.Descriptor instead.
+func (ReadConsistency) EnumDescriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{0}
+}
+
+type EntryType int32
+
+const (
+	EntryType_ENTRY_PUT    EntryType = 0
+	EntryType_ENTRY_NOOP   EntryType = 1 // Appended by a new leader to commit entries from earlier terms
+	EntryType_ENTRY_DELETE EntryType = 2
+	EntryType_ENTRY_CONFIG EntryType = 3 // Changes the cluster configuration as soon as it is appended
+)
+
+// Enum value maps for EntryType.
+var (
+	EntryType_name = map[int32]string{
+		0: "ENTRY_PUT",
+		1: "ENTRY_NOOP",
+		2: "ENTRY_DELETE",
+		3: "ENTRY_CONFIG",
+	}
+	EntryType_value = map[string]int32{
+		"ENTRY_PUT":    0,
+		"ENTRY_NOOP":   1,
+		"ENTRY_DELETE": 2,
+		"ENTRY_CONFIG": 3,
+	}
+)
+
+func (x EntryType) Enum() *EntryType {
+	p := new(EntryType)
+	*p = x
+	return p
+}
+
+func (x EntryType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EntryType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_grassdb_proto_enumTypes[1].Descriptor()
+}
+
+func (EntryType) Type() protoreflect.EnumType {
+	return &file_proto_grassdb_proto_enumTypes[1]
+}
+
+func (x EntryType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EntryType.Descriptor instead.
+func (EntryType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{1}
+}
+
 type TakeSnapshotRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -101,27 +202,26 @@ func (x *TakeSnapshotResponse) GetSuccess() bool {
 	return false
 }
 
-type GetRequest struct {
+type StatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetRequest) Reset() {
-	*x = GetRequest{}
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
 	mi := &file_proto_grassdb_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetRequest) String() string {
+func (x *StatusRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRequest) ProtoMessage() {}
+func (*StatusRequest) ProtoMessage() {}
 
-func (x *GetRequest) ProtoReflect() protoreflect.Message {
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_grassdb_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -133,41 +233,141 @@ func (x *GetRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
-func (*GetRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
 	return file_proto_grassdb_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *GetRequest) GetKey() string {
+type StatusResponse struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Id                     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State                  string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Term                   int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId               string                 `protobuf:"bytes,4,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	CommitIndex            int64                  `protobuf:"varint,5,opt,name=commit_index,json=commitIndex,proto3" json:"commit_index,omitempty"`
+	LastApplied            int64                  `protobuf:"varint,6,opt,name=last_applied,json=lastApplied,proto3" json:"last_applied,omitempty"`
+	LastSnapshotIndex      int64                  `protobuf:"varint,7,opt,name=last_snapshot_index,json=lastSnapshotIndex,proto3" json:"last_snapshot_index,omitempty"`
+	LastSnapshotTimeUnixMs int64                  `protobuf:"varint,8,opt,name=last_snapshot_time_unix_ms,json=lastSnapshotTimeUnixMs,proto3" json:"last_snapshot_time_unix_ms,omitempty"` // 0 if the node has no snapshot
+	Members                []*Member              `protobuf:"bytes,9,rep,name=members,proto3" json:"members,omitempty"`                                                                    // The node's current cluster configuration
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[3]
 	if x != nil {
-		return x.Key
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StatusResponse) GetId() string {
+	if x != nil {
+		return x.Id
 	}
 	return ""
 }
 
-type GetResponse struct {
+func (x *StatusResponse) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetCommitIndex() int64 {
+	if x != nil {
+		return x.CommitIndex
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLastApplied() int64 {
+	if x != nil {
+		return x.LastApplied
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLastSnapshotIndex() int64 {
+	if x != nil {
+		return x.LastSnapshotIndex
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLastSnapshotTimeUnixMs() int64 {
+	if x != nil {
+		return x.LastSnapshotTimeUnixMs
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetMembers() []*Member {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type TransferLeadershipRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
-	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	TargetId      string                 `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"` // Voter to hand leadership to
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetResponse) Reset() {
-	*x = GetResponse{}
-	mi := &file_proto_grassdb_proto_msgTypes[3]
+func (x *TransferLeadershipRequest) Reset() {
+	*x = TransferLeadershipRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetResponse) String() string {
+func (x *TransferLeadershipRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetResponse) ProtoMessage() {}
+func (*TransferLeadershipRequest) ProtoMessage() {}
 
-func (x *GetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[3]
+func (x *TransferLeadershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -178,48 +378,110 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
-func (*GetResponse) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use TransferLeadershipRequest.ProtoReflect.Descriptor instead.
+func (*TransferLeadershipRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *GetResponse) GetValue() string {
+func (x *TransferLeadershipRequest) GetTargetId() string {
 	if x != nil {
-		return x.Value
+		return x.TargetId
 	}
 	return ""
 }
 
-func (x *GetResponse) GetFound() bool {
+type TransferLeadershipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // The new leader on success, otherwise a redirect to the leader
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,4,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"` // gRPC address of leader_id, if known
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferLeadershipResponse) Reset() {
+	*x = TransferLeadershipResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLeadershipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLeadershipResponse) ProtoMessage() {}
+
+func (x *TransferLeadershipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[5]
 	if x != nil {
-		return x.Found
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferLeadershipResponse.ProtoReflect.Descriptor instead.
+func (*TransferLeadershipResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TransferLeadershipResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
 	}
 	return false
 }
 
-type SetRequest struct {
+func (x *TransferLeadershipResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *TransferLeadershipResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TransferLeadershipResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+type AddMemberRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`        // gRPC address of the new member
+	Learner       bool                   `protobuf:"varint,3,opt,name=learner,proto3" json:"learner,omitempty"` // Add as a non-voting learner, to be promoted once caught up
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetRequest) Reset() {
-	*x = SetRequest{}
-	mi := &file_proto_grassdb_proto_msgTypes[4]
+func (x *AddMemberRequest) Reset() {
+	*x = AddMemberRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetRequest) String() string {
+func (x *AddMemberRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetRequest) ProtoMessage() {}
+func (*AddMemberRequest) ProtoMessage() {}
 
-func (x *SetRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[4]
+func (x *AddMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -230,49 +492,644 @@ func (x *SetRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
-func (*SetRequest) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use AddMemberRequest.ProtoReflect.Descriptor instead.
+func (*AddMemberRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *SetRequest) GetKey() string {
+func (x *AddMemberRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AddMemberRequest) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *AddMemberRequest) GetLearner() bool {
+	if x != nil {
+		return x.Learner
+	}
+	return false
+}
+
+type RemoveMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveMemberRequest) Reset() {
+	*x = RemoveMemberRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveMemberRequest) ProtoMessage() {}
+
+func (x *RemoveMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveMemberRequest.ProtoReflect.Descriptor instead.
+func (*RemoveMemberRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RemoveMemberRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// PromoteMemberRequest turns a learner that has caught up into a voter.
+type PromoteMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromoteMemberRequest) Reset() {
+	*x = PromoteMemberRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromoteMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteMemberRequest) ProtoMessage() {}
+
+func (x *PromoteMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteMemberRequest.ProtoReflect.Descriptor instead.
+func (*PromoteMemberRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PromoteMemberRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type MembershipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // Redirect to leader if not leader
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	LeaderAddr    string                 `protobuf:"bytes,4,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"` // gRPC address of the leader, if known
+	Members       []*Member              `protobuf:"bytes,5,rep,name=members,proto3" json:"members,omitempty"`                         // The configuration after the change
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembershipResponse) Reset() {
+	*x = MembershipResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembershipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipResponse) ProtoMessage() {}
+
+func (x *MembershipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipResponse.ProtoReflect.Descriptor instead.
+func (*MembershipResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MembershipResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *MembershipResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *MembershipResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *MembershipResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *MembershipResponse) GetMembers() []*Member {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Key         string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Consistency ReadConsistency        `protobuf:"varint,2,opt,name=consistency,proto3,enum=grassdb.ReadConsistency" json:"consistency,omitempty"`
+	// For READ_STALE: fail unless the node heard from the leader within this
+	// many milliseconds. 0 means any staleness is acceptable.
+	MaxStalenessMs int64 `protobuf:"varint,3,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_READ_LINEARIZABLE
+}
+
+func (x *GetRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *GetResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type SetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRequest) Reset() {
+	*x = SetRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRequest) ProtoMessage() {}
+
+func (x *SetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type SetResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	LeaderId       string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // Redirect to leader if not leader
+	Error          string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	LeaderAddr     string                 `protobuf:"bytes,4,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`               // gRPC address of the leader, if known
+	LeaderHttpAddr string                 `protobuf:"bytes,5,opt,name=leader_http_addr,json=leaderHttpAddr,proto3" json:"leader_http_addr,omitempty"` // HTTP address of the leader, if known
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetResponse) ProtoMessage() {}
+
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *SetResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SetResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *SetResponse) GetLeaderHttpAddr() string {
+	if x != nil {
+		return x.LeaderHttpAddr
+	}
+	return ""
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type DeleteResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	LeaderId       string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // Redirect to leader if not leader
+	Error          string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	LeaderAddr     string                 `protobuf:"bytes,4,opt,name=leader_addr,json=leaderAddr,proto3" json:"leader_addr,omitempty"`               // gRPC address of the leader, if known
+	LeaderHttpAddr string                 `protobuf:"bytes,5,opt,name=leader_http_addr,json=leaderHttpAddr,proto3" json:"leader_http_addr,omitempty"` // HTTP address of the leader, if known
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *DeleteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeleteResponse) GetLeaderAddr() string {
+	if x != nil {
+		return x.LeaderAddr
+	}
+	return ""
+}
+
+func (x *DeleteResponse) GetLeaderHttpAddr() string {
+	if x != nil {
+		return x.LeaderHttpAddr
+	}
+	return ""
+}
+
+// Member is a node of the cluster.
+type Member struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`        // gRPC address
+	Learner       bool                   `protobuf:"varint,3,opt,name=learner,proto3" json:"learner,omitempty"` // Receives the log but does not vote or count toward quorum
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Member) Reset() {
+	*x = Member{}
+	mi := &file_proto_grassdb_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Member) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Member) ProtoMessage() {}
+
+func (x *Member) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Member.ProtoReflect.Descriptor instead.
+func (*Member) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Member) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Member) GetAddr() string {
 	if x != nil {
-		return x.Key
+		return x.Addr
 	}
 	return ""
 }
 
-func (x *SetRequest) GetValue() string {
+func (x *Member) GetLearner() bool {
 	if x != nil {
-		return x.Value
+		return x.Learner
 	}
-	return ""
+	return false
 }
 
-type SetResponse struct {
+// ClusterConfig lists the members of the cluster. It is carried by
+// ENTRY_CONFIG entries and stored with snapshots.
+type ClusterConfig struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // Redirect to leader if not leader
-	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Members       []*Member              `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetResponse) Reset() {
-	*x = SetResponse{}
-	mi := &file_proto_grassdb_proto_msgTypes[5]
+func (x *ClusterConfig) Reset() {
+	*x = ClusterConfig{}
+	mi := &file_proto_grassdb_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetResponse) String() string {
+func (x *ClusterConfig) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetResponse) ProtoMessage() {}
+func (*ClusterConfig) ProtoMessage() {}
 
-func (x *SetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[5]
+func (x *ClusterConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -283,30 +1140,16 @@ func (x *SetResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
-func (*SetResponse) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{5}
-}
-
-func (x *SetResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *SetResponse) GetLeaderId() string {
-	if x != nil {
-		return x.LeaderId
-	}
-	return ""
+// Deprecated: Use ClusterConfig.ProtoReflect.Descriptor instead.
+func (*ClusterConfig) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *SetResponse) GetError() string {
+func (x *ClusterConfig) GetMembers() []*Member {
 	if x != nil {
-		return x.Error
+		return x.Members
 	}
-	return ""
+	return nil
 }
 
 type LogEntry struct {
@@ -314,13 +1157,15 @@ type LogEntry struct {
 	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
 	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
 	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Type          EntryType              `protobuf:"varint,4,opt,name=type,proto3,enum=grassdb.EntryType" json:"type,omitempty"`
+	Config        *ClusterConfig         `protobuf:"bytes,5,opt,name=config,proto3" json:"config,omitempty"` // For ENTRY_CONFIG
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *LogEntry) Reset() {
 	*x = LogEntry{}
-	mi := &file_proto_grassdb_proto_msgTypes[6]
+	mi := &file_proto_grassdb_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -332,7 +1177,7 @@ func (x *LogEntry) String() string {
 func (*LogEntry) ProtoMessage() {}
 
 func (x *LogEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[6]
+	mi := &file_proto_grassdb_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -345,7 +1190,7 @@ func (x *LogEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
 func (*LogEntry) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{6}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *LogEntry) GetTerm() int64 {
@@ -369,19 +1214,36 @@ func (x *LogEntry) GetValue() string {
 	return ""
 }
 
+func (x *LogEntry) GetType() EntryType {
+	if x != nil {
+		return x.Type
+	}
+	return EntryType_ENTRY_PUT
+}
+
+func (x *LogEntry) GetConfig() *ClusterConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
 type RequestVoteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
-	CandidateId   string                 `protobuf:"bytes,2,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
-	LastLogIndex  int64                  `protobuf:"varint,3,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
-	LastLogTerm   int64                  `protobuf:"varint,4,opt,name=last_log_term,json=lastLogTerm,proto3" json:"last_log_term,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Term         int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	CandidateId  string                 `protobuf:"bytes,2,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
+	LastLogIndex int64                  `protobuf:"varint,3,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
+	LastLogTerm  int64                  `protobuf:"varint,4,opt,name=last_log_term,json=lastLogTerm,proto3" json:"last_log_term,omitempty"`
+	// Set by a candidate the leader asked to take over with TimeoutNow; voters
+	// grant it even while they still hear from that leader.
+	LeadershipTransfer bool `protobuf:"varint,5,opt,name=leadership_transfer,json=leadershipTransfer,proto3" json:"leadership_transfer,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *RequestVoteRequest) Reset() {
 	*x = RequestVoteRequest{}
-	mi := &file_proto_grassdb_proto_msgTypes[7]
+	mi := &file_proto_grassdb_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -393,7 +1255,7 @@ func (x *RequestVoteRequest) String() string {
 func (*RequestVoteRequest) ProtoMessage() {}
 
 func (x *RequestVoteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[7]
+	mi := &file_proto_grassdb_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -406,7 +1268,7 @@ func (x *RequestVoteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestVoteRequest.ProtoReflect.Descriptor instead.
 func (*RequestVoteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{7}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *RequestVoteRequest) GetTerm() int64 {
@@ -437,6 +1299,13 @@ func (x *RequestVoteRequest) GetLastLogTerm() int64 {
 	return 0
 }
 
+func (x *RequestVoteRequest) GetLeadershipTransfer() bool {
+	if x != nil {
+		return x.LeadershipTransfer
+	}
+	return false
+}
+
 type RequestVoteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
@@ -447,7 +1316,7 @@ type RequestVoteResponse struct {
 
 func (x *RequestVoteResponse) Reset() {
 	*x = RequestVoteResponse{}
-	mi := &file_proto_grassdb_proto_msgTypes[8]
+	mi := &file_proto_grassdb_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -459,7 +1328,7 @@ func (x *RequestVoteResponse) String() string {
 func (*RequestVoteResponse) ProtoMessage() {}
 
 func (x *RequestVoteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[8]
+	mi := &file_proto_grassdb_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -472,7 +1341,7 @@ func (x *RequestVoteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestVoteResponse.ProtoReflect.Descriptor instead.
 func (*RequestVoteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{8}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *RequestVoteResponse) GetTerm() int64 {
@@ -503,7 +1372,7 @@ type AppendEntriesRequest struct {
 
 func (x *AppendEntriesRequest) Reset() {
 	*x = AppendEntriesRequest{}
-	mi := &file_proto_grassdb_proto_msgTypes[9]
+	mi := &file_proto_grassdb_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -515,7 +1384,7 @@ func (x *AppendEntriesRequest) String() string {
 func (*AppendEntriesRequest) ProtoMessage() {}
 
 func (x *AppendEntriesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[9]
+	mi := &file_proto_grassdb_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,7 +1397,7 @@ func (x *AppendEntriesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AppendEntriesRequest.ProtoReflect.Descriptor instead.
 func (*AppendEntriesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{9}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *AppendEntriesRequest) GetTerm() int64 {
@@ -577,13 +1446,14 @@ type AppendEntriesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
 	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	ConflictIndex int64                  `protobuf:"varint,3,opt,name=conflict_index,json=conflictIndex,proto3" json:"conflict_index,omitempty"` // On failure, the index the leader should retry from
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AppendEntriesResponse) Reset() {
 	*x = AppendEntriesResponse{}
-	mi := &file_proto_grassdb_proto_msgTypes[10]
+	mi := &file_proto_grassdb_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -595,7 +1465,7 @@ func (x *AppendEntriesResponse) String() string {
 func (*AppendEntriesResponse) ProtoMessage() {}
 
 func (x *AppendEntriesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[10]
+	mi := &file_proto_grassdb_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -608,7 +1478,7 @@ func (x *AppendEntriesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AppendEntriesResponse.ProtoReflect.Descriptor instead.
 func (*AppendEntriesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{10}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *AppendEntriesResponse) GetTerm() int64 {
@@ -625,20 +1495,29 @@ func (x *AppendEntriesResponse) GetSuccess() bool {
 	return false
 }
 
+func (x *AppendEntriesResponse) GetConflictIndex() int64 {
+	if x != nil {
+		return x.ConflictIndex
+	}
+	return 0
+}
+
 type InstallSnapshotRequest struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	Term              int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
 	LeaderId          string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
 	LastIncludedIndex int64                  `protobuf:"varint,3,opt,name=last_included_index,json=lastIncludedIndex,proto3" json:"last_included_index,omitempty"`
 	LastIncludedTerm  int64                  `protobuf:"varint,4,opt,name=last_included_term,json=lastIncludedTerm,proto3" json:"last_included_term,omitempty"`
-	Data              []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"` // The snapshot chunks (simplified: all data in one chunk for now)
+	Data              []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`      // A chunk of the snapshot file, starting at offset
+	Offset            int64                  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"` // Byte offset of this chunk in the snapshot file
+	Done              bool                   `protobuf:"varint,7,opt,name=done,proto3" json:"done,omitempty"`     // True for the last chunk
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *InstallSnapshotRequest) Reset() {
 	*x = InstallSnapshotRequest{}
-	mi := &file_proto_grassdb_proto_msgTypes[11]
+	mi := &file_proto_grassdb_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -650,7 +1529,7 @@ func (x *InstallSnapshotRequest) String() string {
 func (*InstallSnapshotRequest) ProtoMessage() {}
 
 func (x *InstallSnapshotRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[11]
+	mi := &file_proto_grassdb_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -663,7 +1542,7 @@ func (x *InstallSnapshotRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InstallSnapshotRequest.ProtoReflect.Descriptor instead.
 func (*InstallSnapshotRequest) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{11}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *InstallSnapshotRequest) GetTerm() int64 {
@@ -701,16 +1580,31 @@ func (x *InstallSnapshotRequest) GetData() []byte {
 	return nil
 }
 
+func (x *InstallSnapshotRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
 type InstallSnapshotResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"` // False if the chunk was out of order; the leader starts over
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *InstallSnapshotResponse) Reset() {
 	*x = InstallSnapshotResponse{}
-	mi := &file_proto_grassdb_proto_msgTypes[12]
+	mi := &file_proto_grassdb_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -722,7 +1616,7 @@ func (x *InstallSnapshotResponse) String() string {
 func (*InstallSnapshotResponse) ProtoMessage() {}
 
 func (x *InstallSnapshotResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_grassdb_proto_msgTypes[12]
+	mi := &file_proto_grassdb_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -735,7 +1629,7 @@ func (x *InstallSnapshotResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InstallSnapshotResponse.ProtoReflect.Descriptor instead.
 func (*InstallSnapshotResponse) Descriptor() ([]byte, []int) {
-	return file_proto_grassdb_proto_rawDescGZIP(), []int{12}
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *InstallSnapshotResponse) GetTerm() int64 {
@@ -745,6 +1639,217 @@ func (x *InstallSnapshotResponse) GetTerm() int64 {
 	return 0
 }
 
+func (x *InstallSnapshotResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// TimeoutNow is sent by a leader handing leadership to an up-to-date follower,
+// which starts an election immediately.
+type TimeoutNowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeoutNowRequest) Reset() {
+	*x = TimeoutNowRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeoutNowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeoutNowRequest) ProtoMessage() {}
+
+func (x *TimeoutNowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeoutNowRequest.ProtoReflect.Descriptor instead.
+func (*TimeoutNowRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TimeoutNowRequest) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *TimeoutNowRequest) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+type TimeoutNowResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          int64                  `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"` // False if the receiver will not stand for election
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeoutNowResponse) Reset() {
+	*x = TimeoutNowResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeoutNowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeoutNowResponse) ProtoMessage() {}
+
+func (x *TimeoutNowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeoutNowResponse.ProtoReflect.Descriptor instead.
+func (*TimeoutNowResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TimeoutNowResponse) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *TimeoutNowResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// ReadIndex lets a follower serve a linearizable read: the leader confirms it
+// is still leader and returns its commit index, which the follower waits to apply.
+type ReadIndexRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadIndexRequest) Reset() {
+	*x = ReadIndexRequest{}
+	mi := &file_proto_grassdb_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadIndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadIndexRequest) ProtoMessage() {}
+
+func (x *ReadIndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadIndexRequest.ProtoReflect.Descriptor instead.
+func (*ReadIndexRequest) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{27}
+}
+
+type ReadIndexResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ReadIndex     int64                  `protobuf:"varint,2,opt,name=read_index,json=readIndex,proto3" json:"read_index,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,3,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // Set when the receiver is not the leader
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadIndexResponse) Reset() {
+	*x = ReadIndexResponse{}
+	mi := &file_proto_grassdb_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadIndexResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadIndexResponse) ProtoMessage() {}
+
+func (x *ReadIndexResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grassdb_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadIndexResponse.ProtoReflect.Descriptor instead.
+func (*ReadIndexResponse) Descriptor() ([]byte, []int) {
+	return file_proto_grassdb_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ReadIndexResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReadIndexResponse) GetReadIndex() int64 {
+	if x != nil {
+		return x.ReadIndex
+	}
+	return 0
+}
+
+func (x *ReadIndexResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
 var File_proto_grassdb_proto protoreflect.FileDescriptor
 
 const file_proto_grassdb_proto_rawDesc = "" +
@@ -752,30 +1857,87 @@ const file_proto_grassdb_proto_rawDesc = "" +
 	"\x13proto/grassdb.proto\x12\agrassdb\"\x15\n" +
 	"\x13TakeSnapshotRequest\"0\n" +
 	"\x14TakeSnapshotResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x1e\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x0f\n" +
+	"\rStatusRequest\"\xc4\x02\n" +
+	"\x0eStatusResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\x12\x1b\n" +
+	"\tleader_id\x18\x04 \x01(\tR\bleaderId\x12!\n" +
+	"\fcommit_index\x18\x05 \x01(\x03R\vcommitIndex\x12!\n" +
+	"\flast_applied\x18\x06 \x01(\x03R\vlastApplied\x12.\n" +
+	"\x13last_snapshot_index\x18\a \x01(\x03R\x11lastSnapshotIndex\x12:\n" +
+	"\x1alast_snapshot_time_unix_ms\x18\b \x01(\x03R\x16lastSnapshotTimeUnixMs\x12)\n" +
+	"\amembers\x18\t \x03(\v2\x0f.grassdb.MemberR\amembers\"8\n" +
+	"\x19TransferLeadershipRequest\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\"\x8a\x01\n" +
+	"\x1aTransferLeadershipResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vleader_addr\x18\x04 \x01(\tR\n" +
+	"leaderAddr\"P\n" +
+	"\x10AddMemberRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x18\n" +
+	"\alearner\x18\x03 \x01(\bR\alearner\"%\n" +
+	"\x13RemoveMemberRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"&\n" +
+	"\x14PromoteMemberRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xad\x01\n" +
+	"\x12MembershipResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vleader_addr\x18\x04 \x01(\tR\n" +
+	"leaderAddr\x12)\n" +
+	"\amembers\x18\x05 \x03(\v2\x0f.grassdb.MemberR\amembers\"\x84\x01\n" +
 	"\n" +
 	"GetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"9\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12:\n" +
+	"\vconsistency\x18\x02 \x01(\x0e2\x18.grassdb.ReadConsistencyR\vconsistency\x12(\n" +
+	"\x10max_staleness_ms\x18\x03 \x01(\x03R\x0emaxStalenessMs\"9\n" +
 	"\vGetResponse\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
 	"\x05found\x18\x02 \x01(\bR\x05found\"4\n" +
 	"\n" +
 	"SetRequest\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"Z\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\xa5\x01\n" +
 	"\vSetResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
 	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\"F\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vleader_addr\x18\x04 \x01(\tR\n" +
+	"leaderAddr\x12(\n" +
+	"\x10leader_http_addr\x18\x05 \x01(\tR\x0eleaderHttpAddr\"!\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\xa8\x01\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vleader_addr\x18\x04 \x01(\tR\n" +
+	"leaderAddr\x12(\n" +
+	"\x10leader_http_addr\x18\x05 \x01(\tR\x0eleaderHttpAddr\"F\n" +
+	"\x06Member\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x18\n" +
+	"\alearner\x18\x03 \x01(\bR\alearner\":\n" +
+	"\rClusterConfig\x12)\n" +
+	"\amembers\x18\x01 \x03(\v2\x0f.grassdb.MemberR\amembers\"\x9e\x01\n" +
 	"\bLogEntry\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x10\n" +
 	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\tR\x05value\"\x95\x01\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12&\n" +
+	"\x04type\x18\x04 \x01(\x0e2\x12.grassdb.EntryTypeR\x04type\x12.\n" +
+	"\x06config\x18\x05 \x01(\v2\x16.grassdb.ClusterConfigR\x06config\"\xc6\x01\n" +
 	"\x12RequestVoteRequest\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\x03R\x04term\x12!\n" +
 	"\fcandidate_id\x18\x02 \x01(\tR\vcandidateId\x12$\n" +
 	"\x0elast_log_index\x18\x03 \x01(\x03R\flastLogIndex\x12\"\n" +
-	"\rlast_log_term\x18\x04 \x01(\x03R\vlastLogTerm\"L\n" +
+	"\rlast_log_term\x18\x04 \x01(\x03R\vlastLogTerm\x12/\n" +
+	"\x13leadership_transfer\x18\x05 \x01(\bR\x12leadershipTransfer\"L\n" +
 	"\x13RequestVoteResponse\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\x03R\x04term\x12!\n" +
 	"\fvote_granted\x18\x02 \x01(\bR\vvoteGranted\"\xe3\x01\n" +
@@ -785,25 +1947,63 @@ const file_proto_grassdb_proto_rawDesc = "" +
 	"\x0eprev_log_index\x18\x03 \x01(\x03R\fprevLogIndex\x12\"\n" +
 	"\rprev_log_term\x18\x04 \x01(\x03R\vprevLogTerm\x12+\n" +
 	"\aentries\x18\x05 \x03(\v2\x11.grassdb.LogEntryR\aentries\x12#\n" +
-	"\rleader_commit\x18\x06 \x01(\x03R\fleaderCommit\"E\n" +
+	"\rleader_commit\x18\x06 \x01(\x03R\fleaderCommit\"l\n" +
 	"\x15AppendEntriesResponse\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x18\n" +
-	"\asuccess\x18\x02 \x01(\bR\asuccess\"\xbb\x01\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12%\n" +
+	"\x0econflict_index\x18\x03 \x01(\x03R\rconflictIndex\"\xe7\x01\n" +
 	"\x16InstallSnapshotRequest\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x1b\n" +
 	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12.\n" +
 	"\x13last_included_index\x18\x03 \x01(\x03R\x11lastIncludedIndex\x12,\n" +
 	"\x12last_included_term\x18\x04 \x01(\x03R\x10lastIncludedTerm\x12\x12\n" +
-	"\x04data\x18\x05 \x01(\fR\x04data\"-\n" +
+	"\x04data\x18\x05 \x01(\fR\x04data\x12\x16\n" +
+	"\x06offset\x18\x06 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04done\x18\a \x01(\bR\x04done\"G\n" +
 	"\x17InstallSnapshotResponse\x12\x12\n" +
-	"\x04term\x18\x01 \x01(\x03R\x04term2\xab\x03\n" +
+	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\"D\n" +
+	"\x11TimeoutNowRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\"B\n" +
+	"\x12TimeoutNowResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x03R\x04term\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\"\x12\n" +
+	"\x10ReadIndexRequest\"i\n" +
+	"\x11ReadIndexResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
+	"\n" +
+	"read_index\x18\x02 \x01(\x03R\treadIndex\x12\x1b\n" +
+	"\tleader_id\x18\x03 \x01(\tR\bleaderId*H\n" +
+	"\x0fReadConsistency\x12\x15\n" +
+	"\x11READ_LINEARIZABLE\x10\x00\x12\x0e\n" +
+	"\n" +
+	"READ_LEASE\x10\x01\x12\x0e\n" +
+	"\n" +
+	"READ_STALE\x10\x02*N\n" +
+	"\tEntryType\x12\r\n" +
+	"\tENTRY_PUT\x10\x00\x12\x0e\n" +
+	"\n" +
+	"ENTRY_NOOP\x10\x01\x12\x10\n" +
+	"\fENTRY_DELETE\x10\x02\x12\x10\n" +
+	"\fENTRY_CONFIG\x10\x032\xae\b\n" +
 	"\bDatabase\x120\n" +
 	"\x03Get\x12\x13.grassdb.GetRequest\x1a\x14.grassdb.GetResponse\x120\n" +
-	"\x03Set\x12\x13.grassdb.SetRequest\x1a\x14.grassdb.SetResponse\x12H\n" +
-	"\vRequestVote\x12\x1b.grassdb.RequestVoteRequest\x1a\x1c.grassdb.RequestVoteResponse\x12N\n" +
+	"\x03Set\x12\x13.grassdb.SetRequest\x1a\x14.grassdb.SetResponse\x129\n" +
+	"\x06Delete\x12\x16.grassdb.DeleteRequest\x1a\x17.grassdb.DeleteResponse\x12H\n" +
+	"\vRequestVote\x12\x1b.grassdb.RequestVoteRequest\x1a\x1c.grassdb.RequestVoteResponse\x12D\n" +
+	"\aPreVote\x12\x1b.grassdb.RequestVoteRequest\x1a\x1c.grassdb.RequestVoteResponse\x12N\n" +
 	"\rAppendEntries\x12\x1d.grassdb.AppendEntriesRequest\x1a\x1e.grassdb.AppendEntriesResponse\x12T\n" +
-	"\x0fInstallSnapshot\x12\x1f.grassdb.InstallSnapshotRequest\x1a .grassdb.InstallSnapshotResponse\x12K\n" +
-	"\fTakeSnapshot\x12\x1c.grassdb.TakeSnapshotRequest\x1a\x1d.grassdb.TakeSnapshotResponseB)Z'github.com/ranjan42/grassdb/proto;protob\x06proto3"
+	"\x0fInstallSnapshot\x12\x1f.grassdb.InstallSnapshotRequest\x1a .grassdb.InstallSnapshotResponse\x12B\n" +
+	"\tReadIndex\x12\x19.grassdb.ReadIndexRequest\x1a\x1a.grassdb.ReadIndexResponse\x12E\n" +
+	"\n" +
+	"TimeoutNow\x12\x1a.grassdb.TimeoutNowRequest\x1a\x1b.grassdb.TimeoutNowResponse\x12K\n" +
+	"\fTakeSnapshot\x12\x1c.grassdb.TakeSnapshotRequest\x1a\x1d.grassdb.TakeSnapshotResponse\x129\n" +
+	"\x06Status\x12\x16.grassdb.StatusRequest\x1a\x17.grassdb.StatusResponse\x12C\n" +
+	"\tAddMember\x12\x19.grassdb.AddMemberRequest\x1a\x1b.grassdb.MembershipResponse\x12I\n" +
+	"\fRemoveMember\x12\x1c.grassdb.RemoveMemberRequest\x1a\x1b.grassdb.MembershipResponse\x12K\n" +
+	"\rPromoteMember\x12\x1d.grassdb.PromoteMemberRequest\x1a\x1b.grassdb.MembershipResponse\x12]\n" +
+	"\x12TransferLeadership\x12\".grassdb.TransferLeadershipRequest\x1a#.grassdb.TransferLeadershipResponseB)Z'github.com/ranjan42/grassdb/proto;protob\x06proto3"
 
 var (
 	file_proto_grassdb_proto_rawDescOnce sync.Once
@@ -817,41 +2017,84 @@ func file_proto_grassdb_proto_rawDescGZIP() []byte {
 	return file_proto_grassdb_proto_rawDescData
 }
 
-var file_proto_grassdb_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_grassdb_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_grassdb_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_proto_grassdb_proto_goTypes = []any{
-	(*TakeSnapshotRequest)(nil),     // 0: grassdb.TakeSnapshotRequest
-	(*TakeSnapshotResponse)(nil),    // 1: grassdb.TakeSnapshotResponse
-	(*GetRequest)(nil),              // 2: grassdb.GetRequest
-	(*GetResponse)(nil),             // 3: grassdb.GetResponse
-	(*SetRequest)(nil),              // 4: grassdb.SetRequest
-	(*SetResponse)(nil),             // 5: grassdb.SetResponse
-	(*LogEntry)(nil),                // 6: grassdb.LogEntry
-	(*RequestVoteRequest)(nil),      // 7: grassdb.RequestVoteRequest
-	(*RequestVoteResponse)(nil),     // 8: grassdb.RequestVoteResponse
-	(*AppendEntriesRequest)(nil),    // 9: grassdb.AppendEntriesRequest
-	(*AppendEntriesResponse)(nil),   // 10: grassdb.AppendEntriesResponse
-	(*InstallSnapshotRequest)(nil),  // 11: grassdb.InstallSnapshotRequest
-	(*InstallSnapshotResponse)(nil), // 12: grassdb.InstallSnapshotResponse
+	(ReadConsistency)(0),               // 0: grassdb.ReadConsistency
+	(EntryType)(0),                     // 1: grassdb.EntryType
+	(*TakeSnapshotRequest)(nil),        // 2: grassdb.TakeSnapshotRequest
+	(*TakeSnapshotResponse)(nil),       // 3: grassdb.TakeSnapshotResponse
+	(*StatusRequest)(nil),              // 4: grassdb.StatusRequest
+	(*StatusResponse)(nil),             // 5: grassdb.StatusResponse
+	(*TransferLeadershipRequest)(nil),  // 6: grassdb.TransferLeadershipRequest
+	(*TransferLeadershipResponse)(nil), // 7: grassdb.TransferLeadershipResponse
+	(*AddMemberRequest)(nil),           // 8: grassdb.AddMemberRequest
+	(*RemoveMemberRequest)(nil),        // 9: grassdb.RemoveMemberRequest
+	(*PromoteMemberRequest)(nil),       // 10: grassdb.PromoteMemberRequest
+	(*MembershipResponse)(nil),         // 11: grassdb.MembershipResponse
+	(*GetRequest)(nil),                 // 12: grassdb.GetRequest
+	(*GetResponse)(nil),                // 13: grassdb.GetResponse
+	(*SetRequest)(nil),                 // 14: grassdb.SetRequest
+	(*SetResponse)(nil),                // 15: grassdb.SetResponse
+	(*DeleteRequest)(nil),              // 16: grassdb.DeleteRequest
+	(*DeleteResponse)(nil),             // 17: grassdb.DeleteResponse
+	(*Member)(nil),                     // 18: grassdb.Member
+	(*ClusterConfig)(nil),              // 19: grassdb.ClusterConfig
+	(*LogEntry)(nil),                   // 20: grassdb.LogEntry
+	(*RequestVoteRequest)(nil),         // 21: grassdb.RequestVoteRequest
+	(*RequestVoteResponse)(nil),        // 22: grassdb.RequestVoteResponse
+	(*AppendEntriesRequest)(nil),       // 23: grassdb.AppendEntriesRequest
+	(*AppendEntriesResponse)(nil),      // 24: grassdb.AppendEntriesResponse
+	(*InstallSnapshotRequest)(nil),     // 25: grassdb.InstallSnapshotRequest
+	(*InstallSnapshotResponse)(nil),    // 26: grassdb.InstallSnapshotResponse
+	(*TimeoutNowRequest)(nil),          // 27: grassdb.TimeoutNowRequest
+	(*TimeoutNowResponse)(nil),         // 28: grassdb.TimeoutNowResponse
+	(*ReadIndexRequest)(nil),           // 29: grassdb.ReadIndexRequest
+	(*ReadIndexResponse)(nil),          // 30: grassdb.ReadIndexResponse
 }
 var file_proto_grassdb_proto_depIdxs = []int32{
-	6,  // 0: grassdb.AppendEntriesRequest.entries:type_name -> grassdb.LogEntry
-	2,  // 1: grassdb.Database.Get:input_type -> grassdb.GetRequest
-	4,  // 2: grassdb.Database.Set:input_type -> grassdb.SetRequest
-	7,  // 3: grassdb.Database.RequestVote:input_type -> grassdb.RequestVoteRequest
-	9,  // 4: grassdb.Database.AppendEntries:input_type -> grassdb.AppendEntriesRequest
-	11, // 5: grassdb.Database.InstallSnapshot:input_type -> grassdb.InstallSnapshotRequest
-	0,  // 6: grassdb.Database.TakeSnapshot:input_type -> grassdb.TakeSnapshotRequest
-	3,  // 7: grassdb.Database.Get:output_type -> grassdb.GetResponse
-	5,  // 8: grassdb.Database.Set:output_type -> grassdb.SetResponse
-	8,  // 9: grassdb.Database.RequestVote:output_type -> grassdb.RequestVoteResponse
-	10, // 10: grassdb.Database.AppendEntries:output_type -> grassdb.AppendEntriesResponse
-	12, // 11: grassdb.Database.InstallSnapshot:output_type -> grassdb.InstallSnapshotResponse
-	1,  // 12: grassdb.Database.TakeSnapshot:output_type -> grassdb.TakeSnapshotResponse
-	7,  // [7:13] is the sub-list for method output_type
-	1,  // [1:7] is the sub-list for method input_type
-	1,  // [1:1] is the sub-list for extension type_name
-	1,  // [1:1] is the sub-list for extension extendee
-	0,  // [0:1] is the sub-list for field type_name
+	18, // 0: grassdb.StatusResponse.members:type_name -> grassdb.Member
+	18, // 1: grassdb.MembershipResponse.members:type_name -> grassdb.Member
+	0,  // 2: grassdb.GetRequest.consistency:type_name -> grassdb.ReadConsistency
+	18, // 3: grassdb.ClusterConfig.members:type_name -> grassdb.Member
+	1,  // 4: grassdb.LogEntry.type:type_name -> grassdb.EntryType
+	19, // 5: grassdb.LogEntry.config:type_name -> grassdb.ClusterConfig
+	20, // 6: grassdb.AppendEntriesRequest.entries:type_name -> grassdb.LogEntry
+	12, // 7: grassdb.Database.Get:input_type -> grassdb.GetRequest
+	14, // 8: grassdb.Database.Set:input_type -> grassdb.SetRequest
+	16, // 9: grassdb.Database.Delete:input_type -> grassdb.DeleteRequest
+	21, // 10: grassdb.Database.RequestVote:input_type -> grassdb.RequestVoteRequest
+	21, // 11: grassdb.Database.PreVote:input_type -> grassdb.RequestVoteRequest
+	23, // 12: grassdb.Database.AppendEntries:input_type -> grassdb.AppendEntriesRequest
+	25, // 13: grassdb.Database.InstallSnapshot:input_type -> grassdb.InstallSnapshotRequest
+	29, // 14: grassdb.Database.ReadIndex:input_type -> grassdb.ReadIndexRequest
+	27, // 15: grassdb.Database.TimeoutNow:input_type -> grassdb.TimeoutNowRequest
+	2,  // 16: grassdb.Database.TakeSnapshot:input_type -> grassdb.TakeSnapshotRequest
+	4,  // 17: grassdb.Database.Status:input_type -> grassdb.StatusRequest
+	8,  // 18: grassdb.Database.AddMember:input_type -> grassdb.AddMemberRequest
+	9,  // 19: grassdb.Database.RemoveMember:input_type -> grassdb.RemoveMemberRequest
+	10, // 20: grassdb.Database.PromoteMember:input_type -> grassdb.PromoteMemberRequest
+	6,  // 21: grassdb.Database.TransferLeadership:input_type -> grassdb.TransferLeadershipRequest
+	13, // 22: grassdb.Database.Get:output_type -> grassdb.GetResponse
+	15, // 23: grassdb.Database.Set:output_type -> grassdb.SetResponse
+	17, // 24: grassdb.Database.Delete:output_type -> grassdb.DeleteResponse
+	22, // 25: grassdb.Database.RequestVote:output_type -> grassdb.RequestVoteResponse
+	22, // 26: grassdb.Database.PreVote:output_type -> grassdb.RequestVoteResponse
+	24, // 27: grassdb.Database.AppendEntries:output_type -> grassdb.AppendEntriesResponse
+	26, // 28: grassdb.Database.InstallSnapshot:output_type -> grassdb.InstallSnapshotResponse
+	30, // 29: grassdb.Database.ReadIndex:output_type -> grassdb.ReadIndexResponse
+	28, // 30: grassdb.Database.TimeoutNow:output_type -> grassdb.TimeoutNowResponse
+	3,  // 31: grassdb.Database.TakeSnapshot:output_type -> grassdb.TakeSnapshotResponse
+	5,  // 32: grassdb.Database.Status:output_type -> grassdb.StatusResponse
+	11, // 33: grassdb.Database.AddMember:output_type -> grassdb.MembershipResponse
+	11, // 34: grassdb.Database.RemoveMember:output_type -> grassdb.MembershipResponse
+	11, // 35: grassdb.Database.PromoteMember:output_type -> grassdb.MembershipResponse
+	7,  // 36: grassdb.Database.TransferLeadership:output_type -> grassdb.TransferLeadershipResponse
+	22, // [22:37] is the sub-list for method output_type
+	7,  // [7:22] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_grassdb_proto_init() }
@@ -864,13 +2107,14 @@ func file_proto_grassdb_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_grassdb_proto_rawDesc), len(file_proto_grassdb_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   13,
+			NumEnums:      2,
+			NumMessages:   29,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_grassdb_proto_goTypes,
 		DependencyIndexes: file_proto_grassdb_proto_depIdxs,
+		EnumInfos:         file_proto_grassdb_proto_enumTypes,
 		MessageInfos:      file_proto_grassdb_proto_msgTypes,
 	}.Build()
 	File_proto_grassdb_proto = out.File
@@ -4,15 +4,15 @@ trap "kill 0" EXIT
 go build -o grassdb main.go
 
 echo "Starting Node 1..."
-./grassdb -id node1 -addr :50051 -http :8081 -peers :50052,:50053 > node1.log 2>&1 &
+./grassdb -id node1 -addr :50051 -http :8081 -peers node2=:50052,node3=:50053 -peer-http node2=:8082,node3=:8083 > node1.log 2>&1 &
 PID1=$!
 
 echo "Starting Node 2..."
-./grassdb -id node2 -addr :50052 -http :8082 -peers :50051,:50053 > node2.log 2>&1 &
+./grassdb -id node2 -addr :50052 -http :8082 -peers node1=:50051,node3=:50053 -peer-http node1=:8081,node3=:8083 > node2.log 2>&1 &
 PID2=$!
 
 echo "Starting Node 3..."
-./grassdb -id node3 -addr :50053 -http :8083 -peers :50051,:50052 > node3.log 2>&1 &
+./grassdb -id node3 -addr :50053 -http :8083 -peers node1=:50051,node2=:50052 -peer-http node1=:8081,node2=:8082 > node3.log 2>&1 &
 PID3=$!
 
 echo "Cluster started. PIDs: $PID1, $PID2, $PID3"
@@ -36,8 +36,11 @@ export default function Home() {
       } else {
         // Handle "Not Leader" specifically
         if (data.error === "Not Leader" && data.leader_id) {
-          const leaderPort = NODE_PORTS[data.leader_id];
-          const leaderUrl = `http://localhost:${leaderPort}`;
+          // Prefer the address the cluster reports, falling back to the local port map
+          const leaderHttpAddr: string | undefined = data.leader_http_addr;
+          const leaderUrl = leaderHttpAddr
+            ? `http://${leaderHttpAddr.startsWith(':') ? `localhost${leaderHttpAddr}` : leaderHttpAddr}`
+            : `http://localhost:${NODE_PORTS[data.leader_id]}`;
 
           setStatus(`Error: Not Leader. Leader is ${data.leader_id}. Switching to ${leaderUrl}...`);
           setDbUrl(leaderUrl);